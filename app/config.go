@@ -0,0 +1,257 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/kalynv/coinbase-pinger/app/probe"
+)
+
+const (
+	StdoutOutput    string = "stdout"
+	JSONOutput      string = "json"
+	K8sPodOutput    string = "k8s-pod"
+	K8sStatusOutput string = "k8s-status"
+)
+
+// config is the pinger configuration collected from flags, environment and
+// positional arguments.
+type config struct {
+	Output             string
+	WebhookURL         string
+	TerminationLog     string
+	Request            probe.Request
+	Repeat             int
+	RepeatDelay        time.Duration
+	InsecureSkipVerify bool
+	// ProxyUsername and ProxyPassword authenticate to the proxy taken from
+	// the standard HTTP_PROXY, HTTPS_PROXY and NO_PROXY variables.
+	ProxyUsername string
+	ProxyPassword string
+
+	// Probe is the probe type, settings of the other types are ignored.
+	Probe          string
+	DNSRecordType  string
+	DNSServer      string
+	TLSServerName  string
+	TLSMinValidity time.Duration
+	GRPCService    string
+	GRPCTLS        bool
+	WebSocket      probe.WebSocketProber
+
+	Kubeconfig      string
+	PingerName      string
+	PingerNamespace string
+
+	LogLevel     string
+	LogBodyLimit int
+}
+
+type assertionsFlag []probe.Assertion
+
+func (f *assertionsFlag) String() string {
+	values := make([]string, 0, len(*f))
+	for _, a := range *f {
+		values = append(values, a.String())
+	}
+	return strings.Join(values, ",")
+}
+
+func (f *assertionsFlag) Set(value string) error {
+	a, err := probe.ParseAssertion(value)
+	if err != nil {
+		return err
+	}
+	*f = append(*f, a)
+	return nil
+}
+
+// parseConfig reads the configuration. Without flags it behaves as the
+// in-cluster pinger always did: BASE_URL plus the path argument is pinged
+// once and the result is written to this pod metadata.
+func parseConfig(args []string, output io.Writer) (config, error) {
+	c := config{}
+	var assertions assertionsFlag
+
+	flags := flag.NewFlagSet("webping", flag.ContinueOnError)
+	flags.SetOutput(output)
+	flags.Usage = func() {
+		fmt.Fprintln(output, "Usage: webping [flags] [path]")
+		fmt.Fprintln(output, "Pings BASE_URL + path, or --url, and reports the result.")
+		flags.PrintDefaults()
+	}
+	flags.StringVar(&c.Output, "output", K8sPodOutput,
+		"Where to report results: stdout, json, k8s-pod or k8s-status.")
+	flags.StringVar(&c.WebhookURL, "webhook-url", "",
+		"URL to post results to as JSON, in addition to the output.")
+	flags.StringVar(&c.Probe, "probe", probe.HTTPProbe, "Probe type: http, tcp, dns, tls, grpc or websocket.")
+	flags.StringVar(&c.Request.URL, "url", "",
+		"URL to ping. Defaults to the "+BaseURLEnv+" environment variable plus the path argument. "+
+			"Probes other than http take their target here or as the argument: "+
+			"host:port for tcp, tls and grpc, a name for dns and a ws or wss URL for websocket.")
+	flags.StringVar(&c.Request.Method, "method", http.MethodGet, "HTTP method of the ping request.")
+	flags.Var(&assertions, "assert",
+		"Response assertion in kind=argument form, may be repeated. Kinds: "+
+			"status, body-contains, header, max-latency, json. Defaults to status=2xx.")
+	flags.DurationVar(&c.Request.Timeout, "timeout", 30*time.Second, "Timeout of a single ping.")
+	flags.IntVar(&c.Repeat, "repeat", 1, "Number of pings to run.")
+	flags.DurationVar(&c.RepeatDelay, "repeat-delay", time.Second, "Delay between repeated pings.")
+	flags.BoolVar(&c.InsecureSkipVerify, "insecure-skip-verify", true,
+		"Skip TLS certificate verification of the pinged server.")
+	flags.StringVar(&c.DNSRecordType, "dns-record-type", "A",
+		"Record type dns probes look up: "+strings.Join(probe.DNSRecordTypes, ", ")+".")
+	flags.StringVar(&c.DNSServer, "dns-server", "",
+		"Server dns probes ask, as host or host:port. The system resolver is used when empty.")
+	flags.StringVar(&c.TLSServerName, "tls-server-name", "",
+		"Name tls probes verify the certificate for. Defaults to the target host.")
+	flags.DurationVar(&c.TLSMinValidity, "tls-min-validity", 0,
+		"How long the certificate must remain valid at least for tls probes to succeed.")
+	flags.StringVar(&c.GRPCService, "grpc-service", "",
+		"Service grpc probes check the health of. The server as a whole is checked when empty.")
+	flags.BoolVar(&c.GRPCTLS, "grpc-tls", false, "Connect to the grpc target with TLS.")
+	flags.StringVar(&c.WebSocket.Subscribe, "ws-subscribe", "",
+		"Message websocket probes send once connected, like a ticker channel subscription.")
+	flags.IntVar(&c.WebSocket.Messages, "ws-messages", 1,
+		"Number of timestamped messages websocket probes wait for.")
+	flags.DurationVar(&c.WebSocket.Within, "ws-within", 0,
+		"How long after subscribing the messages must arrive. Defaults to the rest of --timeout.")
+	flags.DurationVar(&c.WebSocket.MaxStaleness, "ws-max-staleness", 0,
+		"Largest age of a message against its server timestamp, unchecked when 0.")
+	flags.StringVar(&c.WebSocket.TimeField, "ws-time-field", "time",
+		"JSON path of the server timestamp in websocket messages.")
+	flags.StringVar(&c.Kubeconfig, "kubeconfig", "",
+		"Path to a kubeconfig for k8s outputs. In-cluster config is used when empty.")
+	flags.StringVar(&c.PingerName, "pinger-name", "",
+		"CoinbasePinger to write results to with --output=k8s-status.")
+	flags.StringVar(&c.PingerNamespace, "pinger-namespace", "",
+		"Namespace of the CoinbasePinger. Defaults to the namespace of this pod.")
+
+	flags.StringVar(&c.TerminationLog, "termination-log", probe.DefaultTerminationLogPath,
+		"File to write the latest result to as the container termination message. "+
+			"Written only with k8s outputs unless set explicitly, empty disables it.")
+	flags.StringVar(&c.LogLevel, "log-level", "info", "Log level: debug, info or error.")
+	flags.IntVar(&c.LogBodyLimit, "log-body-limit", 1024,
+		"Maximum number of response body bytes logged at debug level, -1 logs the whole body.")
+
+	if err := flags.Parse(args); err != nil {
+		return c, err
+	}
+
+	c.Request.Assertions = assertions
+	c.ProxyUsername = os.Getenv(ProxyUsernameEnv)
+	c.ProxyPassword = os.Getenv(ProxyPasswordEnv)
+
+	switch c.Output {
+	case StdoutOutput, JSONOutput:
+		if !isFlagSet(flags, "termination-log") {
+			c.TerminationLog = ""
+		}
+	case K8sPodOutput:
+	case K8sStatusOutput:
+		if c.PingerName == "" {
+			return c, errors.New("--pinger-name is required with --output=k8s-status")
+		}
+	default:
+		return c, fmt.Errorf("unknown output %q", c.Output)
+	}
+	if c.Repeat < 1 {
+		return c, errors.New("--repeat must be at least 1")
+	}
+
+	switch c.Probe {
+	case probe.HTTPProbe:
+	case probe.TCPProbe, probe.DNSProbe, probe.TLSProbe, probe.GRPCProbe, probe.WebSocketProbe:
+		err := parseTarget(&c, flags)
+		return c, err
+	default:
+		return c, fmt.Errorf("unknown probe %q", c.Probe)
+	}
+
+	if c.Request.URL == "" {
+		pingURL, err := getPingURL(flags.Arg(0))
+		if err != nil {
+			return c, err
+		}
+		c.Request.URL = pingURL
+	}
+	if _, err := url.ParseRequestURI(c.Request.URL); err != nil {
+		return c, fmt.Errorf("bad ping URL: %w", err)
+	}
+
+	return c, nil
+}
+
+// parseTarget validates the target and settings of probes other than http.
+func parseTarget(c *config, flags *flag.FlagSet) error {
+	if c.Request.URL == "" {
+		c.Request.URL = flags.Arg(0)
+	}
+	if c.Request.URL == "" {
+		return fmt.Errorf("--url or a target argument is required with --probe=%s", c.Probe)
+	}
+	if c.Probe == probe.DNSProbe && !containsFold(probe.DNSRecordTypes, c.DNSRecordType) {
+		return fmt.Errorf("unknown DNS record type %q", c.DNSRecordType)
+	}
+	if c.Probe == probe.WebSocketProbe {
+		if c.WebSocket.Messages < 1 {
+			return errors.New("--ws-messages must be at least 1")
+		}
+		if target, err := url.Parse(c.Request.URL); err != nil || (target.Scheme != "ws" && target.Scheme != "wss") {
+			return fmt.Errorf("websocket target %q must be a ws or wss URL", c.Request.URL)
+		}
+	}
+	return nil
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func isFlagSet(flags *flag.FlagSet, name string) bool {
+	set := false
+	flags.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
+func getPingURL(path string) (string, error) {
+	baseURL := os.Getenv(BaseURLEnv)
+	pingURL, err := url.Parse(baseURL + path)
+	if err != nil {
+		return "", err
+	}
+	return pingURL.String(), nil
+}
+
+func getNamespace() (string, error) {
+	path := os.Getenv(NamespaceFilePathEnv)
+	if path == "" {
+		path = "/etc/podinfo/namespace"
+	}
+	namespace, err := os.ReadFile(path)
+	return string(namespace), err
+}
+
+func getPodName() (string, error) {
+	path := os.Getenv(PodNameFilePathEnv)
+	if path == "" {
+		path = "/etc/podinfo/name"
+	}
+	name, err := os.ReadFile(path)
+	return string(name), err
+}
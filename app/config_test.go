@@ -0,0 +1,151 @@
+package main
+
+import (
+	"io"
+	"os"
+	"testing"
+
+	"github.com/kalynv/coinbase-pinger/app/probe"
+)
+
+// setEnv sets the variables for the test and restores them after it.
+func setEnv(t *testing.T, env map[string]string) {
+	t.Helper()
+	for name, value := range env {
+		previous, found := os.LookupEnv(name)
+		if err := os.Setenv(name, value); err != nil {
+			t.Fatal(err)
+		}
+		name := name
+		t.Cleanup(func() {
+			if found {
+				os.Setenv(name, previous)
+			} else {
+				os.Unsetenv(name)
+			}
+		})
+	}
+}
+
+func Test_parseConfig_errors(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		env  map[string]string
+		want string
+	}{
+		{"unknown output", []string{"--output=xml"}, nil, `unknown output "xml"`},
+		{"k8s-status without pinger", []string{"--output=k8s-status"}, nil,
+			"--pinger-name is required with --output=k8s-status"},
+		{"no repeat", []string{"--repeat=0"}, nil, "--repeat must be at least 1"},
+		{"unknown probe", []string{"--probe=icmp"}, nil, `unknown probe "icmp"`},
+		{"probe without target", []string{"--probe=tcp"}, nil,
+			"--url or a target argument is required with --probe=tcp"},
+		{"unknown DNS record type", []string{"--probe=dns", "--dns-record-type=XX", "coinbase.com"}, nil,
+			`unknown DNS record type "XX"`},
+		{"no websocket messages", []string{"--probe=websocket", "--ws-messages=0", "wss://ws-feed.example.org"}, nil,
+			"--ws-messages must be at least 1"},
+		{"http websocket target", []string{"--probe=websocket", "https://ws-feed.example.org"}, nil,
+			`websocket target "https://ws-feed.example.org" must be a ws or wss URL`},
+		{"bad assertion", []string{"--assert=status=ok"}, nil,
+			`invalid value "status=ok" for flag -assert: assertion "status=ok": status must be a code or a class like 2xx`},
+		{"bad base URL", []string{"/prices"}, map[string]string{BaseURLEnv: "https://api.example.org/%zz"},
+			`parse "https://api.example.org/%zz/prices": invalid URL escape "%zz"`},
+		{"no ping URL", nil, map[string]string{BaseURLEnv: ""},
+			`bad ping URL: parse "": empty url`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setEnv(t, tt.env)
+			_, err := parseConfig(tt.args, io.Discard)
+			if err == nil {
+				t.Fatalf("Got no error, want [%s]", tt.want)
+			}
+			if err.Error() != tt.want {
+				t.Errorf("Got [%s], want [%s]", err, tt.want)
+			}
+		})
+	}
+}
+
+func Test_parseConfig(t *testing.T) {
+	setEnv(t, map[string]string{
+		BaseURLEnv:       "https://api.example.org/v2",
+		ProxyUsernameEnv: "pinger",
+		ProxyPasswordEnv: "secret",
+	})
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{"http path", []string{"/prices/BTC-USD/buy"}, "https://api.example.org/v2/prices/BTC-USD/buy"},
+		{"http url", []string{"--url=https://status.example.org/"}, "https://status.example.org/"},
+		// other probes return before BASE_URL is looked at
+		{"tcp argument", []string{"--probe=tcp", "api.example.org:443"}, "api.example.org:443"},
+		{"dns url", []string{"--probe=dns", "--url=api.example.org", "--dns-record-type=aaaa"}, "api.example.org"},
+		{"websocket", []string{"--probe=websocket", "wss://ws-feed.example.org"}, "wss://ws-feed.example.org"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := parseConfig(tt.args, io.Discard)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if c.Request.URL != tt.want {
+				t.Errorf("Got [%s], want [%s]", c.Request.URL, tt.want)
+			}
+			if c.ProxyUsername != "pinger" || c.ProxyPassword != "secret" {
+				t.Errorf("Got proxy credentials [%s:%s], want [pinger:secret]", c.ProxyUsername, c.ProxyPassword)
+			}
+		})
+	}
+}
+
+func Test_parseConfig_assertions(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		// the prober falls back to probe.DefaultAssertions
+		{"none", []string{"--url=https://api.example.org"}, ""},
+		{"repeated", []string{"--url=https://api.example.org", "--assert=status=200", "--assert=json=data.amount"},
+			"status=200,json=data.amount"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := parseConfig(tt.args, io.Discard)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := assertionsFlag(c.Request.Assertions)
+			if got.String() != tt.want {
+				t.Errorf("Got [%s], want [%s]", got.String(), tt.want)
+			}
+		})
+	}
+}
+
+func Test_parseConfig_terminationLog(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{"k8s output", []string{"--url=https://api.example.org"}, probe.DefaultTerminationLogPath},
+		{"stdout output", []string{"--output=stdout", "--url=https://api.example.org"}, ""},
+		{"explicit", []string{"--output=json", "--termination-log=/tmp/log", "--url=https://api.example.org"}, "/tmp/log"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := parseConfig(tt.args, io.Discard)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if c.TerminationLog != tt.want {
+				t.Errorf("Got [%s], want [%s]", c.TerminationLog, tt.want)
+			}
+		})
+	}
+}
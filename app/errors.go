@@ -0,0 +1,49 @@
+package main
+
+import (
+	"errors"
+)
+
+// Exit codes of the pinger.
+const (
+	ExitSucceeded    int = 0
+	ExitProbeFailed  int = 1
+	ExitConfigError  int = 2
+	ExitReportFailed int = 3
+)
+
+// ConfigError means the pinger is misconfigured: bad flags, missing
+// environment, downward API files or cluster credentials.
+type ConfigError struct{ Err error }
+
+func (e *ConfigError) Error() string { return "configuration error: " + e.Err.Error() }
+func (e *ConfigError) Unwrap() error { return e.Err }
+
+// ProbeError means the probe ran, but the target failed it.
+type ProbeError struct{ Reason string }
+
+func (e *ProbeError) Error() string { return "probe failed: " + e.Reason }
+
+// ReportError means the result could not be delivered to the output.
+type ReportError struct{ Err error }
+
+func (e *ReportError) Error() string { return "reporting failed: " + e.Err.Error() }
+func (e *ReportError) Unwrap() error { return e.Err }
+
+// exitCode maps an error returned by run to the process exit code.
+func exitCode(err error) int {
+	var configErr *ConfigError
+	var probeErr *ProbeError
+	var reportErr *ReportError
+	switch {
+	case err == nil:
+		return ExitSucceeded
+	case errors.As(err, &configErr):
+		return ExitConfigError
+	case errors.As(err, &reportErr):
+		return ExitReportFailed
+	case errors.As(err, &probeErr):
+		return ExitProbeFailed
+	}
+	return ExitProbeFailed
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func Test_exitCode(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"succeeded", nil, ExitSucceeded},
+		{"config", &ConfigError{Err: errors.New("no BASE_URL")}, ExitConfigError},
+		{"probe", &ProbeError{Reason: "PingFailed"}, ExitProbeFailed},
+		{"report", &ReportError{Err: errors.New("forbidden")}, ExitReportFailed},
+		{"wrapped report", fmt.Errorf("run: %w", &ReportError{Err: errors.New("conflict")}), ExitReportFailed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := exitCode(tt.err); got != tt.want {
+				t.Errorf("Got exit code %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
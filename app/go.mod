@@ -3,6 +3,11 @@ module github.com/kalynv/coinbase-pinger/app
 go 1.16
 
 require (
+	github.com/go-logr/logr v0.4.0
+	github.com/go-logr/zapr v0.4.0
+	go.uber.org/zap v1.19.0
+	golang.org/x/net v0.0.0-20210520170846-37e1c6afe023
+	google.golang.org/grpc v1.40.0
 	k8s.io/api v0.22.1
 	k8s.io/apimachinery v0.22.1
 	k8s.io/client-go v0.22.1
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/go-logr/logr"
+	"github.com/go-logr/zapr"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	PingerUIDEnv string = "PINGER_UID"
+
+	redacted string = "REDACTED"
+)
+
+// sensitiveHeaders are never logged, along with every CB-ACCESS-* header.
+var sensitiveHeaders = map[string]bool{
+	"Authorization":       true,
+	"Proxy-Authorization": true,
+	"Cookie":              true,
+	"Set-Cookie":          true,
+}
+
+// newLogger returns a JSON logger, the same zap and logr pair the operator
+// uses, at the given level: debug, info or error.
+func newLogger(level string) (logr.Logger, *zap.Logger, error) {
+	zapLevel := zapcore.InfoLevel
+	if err := zapLevel.UnmarshalText([]byte(level)); err != nil {
+		return nil, nil, fmt.Errorf("bad log level %q: %w", level, err)
+	}
+
+	config := zap.NewProductionConfig()
+	config.Level = zap.NewAtomicLevelAt(zapLevel)
+	config.EncoderConfig.TimeKey = "ts"
+	config.EncoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
+	config.Sampling = nil
+	config.DisableStacktrace = true
+	zapLogger, err := config.Build()
+	if err != nil {
+		return nil, nil, err
+	}
+	return zapr.NewLogger(zapLogger), zapLogger, nil
+}
+
+// newRunID returns a random ID which correlates log lines of a single run.
+func newRunID() string {
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(id)
+}
+
+// runValues returns the key-value pairs identifying this run: run ID, pod
+// name and CoinbasePinger UID when running in the cluster.
+func runValues(runID string) []interface{} {
+	values := []interface{}{"runID", runID}
+	if podName, err := getPodName(); err == nil {
+		values = append(values, "pod", strings.TrimSpace(podName))
+	}
+	if uid := os.Getenv(PingerUIDEnv); uid != "" {
+		values = append(values, "pingerUID", uid)
+	}
+	return values
+}
+
+// redactHeaders returns a copy of headers with credentials replaced.
+func redactHeaders(headers http.Header) http.Header {
+	redactedHeaders := make(http.Header, len(headers))
+	for name, values := range headers {
+		canonical := http.CanonicalHeaderKey(name)
+		if sensitiveHeaders[canonical] || strings.HasPrefix(canonical, "Cb-Access-") {
+			redactedHeaders[name] = []string{redacted}
+			continue
+		}
+		redactedHeaders[name] = values
+	}
+	return redactedHeaders
+}
+
+// truncate shortens the body to at most limit bytes. Negative limit keeps
+// the whole body.
+func truncate(body string, limit int) string {
+	if limit < 0 || len(body) <= limit {
+		return body
+	}
+	return fmt.Sprintf("%s...(%d bytes truncated)", body[:limit], len(body)-limit)
+}
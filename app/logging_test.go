@@ -0,0 +1,49 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+func Test_redactHeaders(t *testing.T) {
+	headers := http.Header{
+		"Content-Type":  []string{"application/json"},
+		"Authorization": []string{"Bearer token"},
+		"Set-Cookie":    []string{"session=1"},
+		"Cb-Access-Key": []string{"key"},
+	}
+
+	got := redactHeaders(headers)
+
+	want := map[string]string{
+		"Content-Type":  "application/json",
+		"Authorization": redacted,
+		"Set-Cookie":    redacted,
+		"Cb-Access-Key": redacted,
+	}
+	for name, value := range want {
+		if got.Get(name) != value {
+			t.Errorf("Got header %s=[%s], want [%s]", name, got.Get(name), value)
+		}
+	}
+	if headers.Get("Authorization") != "Bearer token" {
+		t.Errorf("redactHeaders changed the original headers")
+	}
+}
+
+func Test_truncate(t *testing.T) {
+	tests := []struct {
+		body  string
+		limit int
+		want  string
+	}{
+		{"short", 10, "short"},
+		{"longer body", 6, "longer...(5 bytes truncated)"},
+		{"whole body", -1, "whole body"},
+	}
+	for _, tt := range tests {
+		if got := truncate(tt.body, tt.limit); got != tt.want {
+			t.Errorf("truncate(%q, %d) = [%s], want [%s]", tt.body, tt.limit, got, tt.want)
+		}
+	}
+}
@@ -2,20 +2,13 @@ package main
 
 import (
 	"context"
-	"crypto/tls"
+	"errors"
+	"flag"
 	"fmt"
-	"io"
-	"log"
-	"net/http"
-	"net/url"
 	"os"
 	"time"
 
-	"k8s.io/client-go/kubernetes"
-	"k8s.io/client-go/rest"
-
-	v1 "k8s.io/api/core/v1"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"github.com/go-logr/logr"
 )
 
 const (
@@ -23,200 +16,99 @@ const (
 	PodNameFilePathEnv    string = "POD_NAME_FILEPATH"
 	MessageAnnotationName string = "MESSAGE_ANNOTATION_NAME"
 	BaseURLEnv            string = "BASE_URL"
-
-	TypeLabel          string = "type"
-	StatusLabel        string = "status"
-	ReasonLabel        string = "reason"
-	MessageAnnotation  string = "message"
-	PingTimeAnnotation string = "ping-time"
-
-	ServiceOffline string = "ServiceOffline"
-	ServiceOnline  string = "ServiceOnline"
-
-	PingSucceeded string = "PingSucceeded"
-	PingFailed    string = "PingFailed"
+	// Proxy credentials are kept out of HTTP_PROXY and HTTPS_PROXY, so
+	// they can be taken from a Secret.
+	ProxyUsernameEnv string = "PROXY_USERNAME"
+	ProxyPasswordEnv string = "PROXY_PASSWORD"
 )
 
-type Result struct {
-	Type     string
-	Status   bool
-	Reason   string
-	Message  string
-	PingTime string
-}
-
 func main() {
-	logger := log.Default()
-
-	logger.Println("Start WebPinger")
-	pingURL, pingURLErr := getPingURL()
-	if pingURLErr != nil {
-		logger.Panic(pingURLErr)
+	c, configErr := parseConfig(os.Args[1:], os.Stderr)
+	if errors.Is(configErr, flag.ErrHelp) {
+		os.Exit(ExitSucceeded)
 	}
-	logger.Println("Ping Url: ", pingURL)
-
-	namespace, namespaceErr := getNamespace()
-	if namespaceErr != nil {
-		logger.Panic(namespaceErr)
-	}
-	logger.Println("Namespace:", namespace)
-
-	podName, podNameErr := getPodName()
-	if podNameErr != nil {
-		logger.Panic(podNameErr)
-	}
-	logger.Println("Pod name:", podName)
-
-	config, configErr := rest.InClusterConfig()
 	if configErr != nil {
-		logger.Panic(configErr)
-	}
-
-	clientset, clientsetErr := kubernetes.NewForConfig(config)
-	if clientsetErr != nil {
-		logger.Panic(clientsetErr)
+		fmt.Fprintln(os.Stderr, "Error:", configErr)
+		os.Exit(ExitConfigError)
 	}
 
-	pingResult, headers, body, pingErr := webPing(
-		prepareHTTPClient(),
-		pingURL,
-		getTime,
-	)
-	if pingErr != nil {
-		logger.Println("Error: ", pingErr)
-	}
-	logger.Println("Headers: ", headers)
-	logger.Println("Response body: ", string(body))
-
-	thisPod, getPodErr := clientset.
-		CoreV1().
-		Pods(namespace).
-		Get(context.Background(), podName, metav1.GetOptions{})
-
-	if getPodErr != nil {
-		logger.Panic(getPodErr)
-	}
-
-	_, updatePodErr := updatePod(context.Background(), clientset, thisPod, pingResult)
-	if updatePodErr != nil {
-		logger.Panic(updatePodErr)
-	}
-
-	logger.Println("Completed WebPinger")
-}
-
-func getPingURL() (string, error) {
-	baseURL := os.Getenv(BaseURLEnv)
-	path := ""
-	if len(os.Args) > 1 {
-		path = os.Args[1]
+	l, zapLogger, loggerErr := newLogger(c.LogLevel)
+	if loggerErr != nil {
+		fmt.Fprintln(os.Stderr, "Error:", loggerErr)
+		os.Exit(ExitConfigError)
 	}
-	pingURL, err := url.Parse(baseURL + path)
-	return pingURL.String(), err
-}
-
-func getNamespace() (string, error) {
-	path := os.Getenv(NamespaceFilePathEnv)
-	if path == "" {
-		path = "/etc/podinfo/namespace"
-	}
-	namespace, err := os.ReadFile(path)
-	return string(namespace), err
-}
+	l = l.WithValues(runValues(newRunID())...)
 
-func getPodName() (string, error) {
-	path := os.Getenv(PodNameFilePathEnv)
-	if path == "" {
-		path = "/etc/podinfo/name"
+	runErr := run(c, l)
+	if runErr != nil {
+		l.Error(runErr, "webpinger failed", "exitCode", exitCode(runErr))
 	}
-	name, err := os.ReadFile(path)
-	return string(name), err
+	_ = zapLogger.Sync()
+	os.Exit(exitCode(runErr))
 }
 
-type timeGetter func() string
-
-func getTime() string {
-	now := time.Now().UTC()
-	timeBody, err := metav1.NewTime(now).MarshalJSON()
-	if err != nil {
-		panic(err)
-	}
-	return string(timeBody)
-}
-
-func prepareHTTPClient() *http.Client {
-	return &http.Client{
-		Transport: &http.Transport{
-			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-		},
-	}
-}
-
-func webPing(
-	client *http.Client,
-	url string,
-	getTime timeGetter,
-) (result Result, headers http.Header, body []byte, err error) {
-	response, err := client.Get(url)
-
-	result = Result{
-		Type:     ServiceOffline,
-		Status:   false,
-		Reason:   PingFailed,
-		Message:  "",
-		PingTime: getTime(),
-	}
-
-	if err != nil {
-		return result, nil, nil, err
-	}
-
-	defer response.Body.Close()
-
-	result.Type = ServiceOnline
-
-	if response.StatusCode >= 200 && response.StatusCode < 300 {
-		result.Status = true
-		result.Reason = PingSucceeded
-	}
-
-	headers = response.Header
-
-	body, err = io.ReadAll(response.Body)
-	if err != nil {
-		result.Status = false
-		result.Reason = PingFailed
-	}
-	result.Message = string(body)
-
-	return result, headers, body, err
-}
-
-func updatePod(
-	ctx context.Context,
-	clientset *kubernetes.Clientset,
-	pod *v1.Pod,
-	updateData Result,
-) (updatedPod *v1.Pod, updateErr error) {
-	updatingPod := pod.DeepCopy()
-
-	if updatingPod.Annotations == nil {
-		updatingPod.Annotations = map[string]string{}
-	}
-	updatingPod.Annotations[MessageAnnotation] = updateData.Message
-	updatingPod.Annotations[PingTimeAnnotation] = updateData.PingTime
-
-	if updatingPod.Labels == nil {
-		updatingPod.Labels = map[string]string{}
-	}
-	updatingPod.Labels[TypeLabel] = updateData.Type
-	updatingPod.Labels[StatusLabel] = fmt.Sprintf("%v", updateData.Status)
-	updatingPod.Labels[ReasonLabel] = updateData.Reason
-
-	updatedPod, updateErr = clientset.
-		CoreV1().
-		Pods(updatingPod.GetNamespace()).
-		Update(ctx, updatingPod, metav1.UpdateOptions{})
+// run pings and reports as configured. It returns a ConfigError, ProbeError
+// or ReportError.
+func run(c config, l logr.Logger) error {
+	l.Info(
+		"start webpinger",
+		"probe", c.Probe,
+		"url", c.Request.URL,
+		"method", c.Request.Method,
+		"output", c.Output,
+	)
 
-	return updatedPod, updateErr
+	sink, sinkErr := newSink(c, os.Stdout)
+	if sinkErr != nil {
+		return &ConfigError{Err: sinkErr}
+	}
+
+	prober, proberErr := newProber(c)
+	if proberErr != nil {
+		return &ConfigError{Err: proberErr}
+	}
+	var probeErr error
+	for i := 0; i < c.Repeat; i++ {
+		if i > 0 {
+			time.Sleep(c.RepeatDelay)
+		}
+
+		pingResult, pingErr := prober.Probe(context.Background(), c.Request)
+		if pingErr != nil {
+			l.Error(pingErr, "ping request failed", "attempt", i+1)
+		}
+		l.V(1).Info(
+			"ping response",
+			"attempt", i+1,
+			"headers", redactHeaders(pingResult.Header),
+			"body", truncate(pingResult.Message, c.LogBodyLimit),
+		)
+		l.Info(
+			"ping finished",
+			"attempt", i+1,
+			"type", pingResult.Type,
+			"status", pingResult.Status,
+			"reason", pingResult.Reason,
+			"statusCode", pingResult.StatusCode,
+			"latencyMs", pingResult.LatencyMs,
+			"failedAssertions", pingResult.FailedAssertions,
+			"proxy", pingResult.Proxy,
+			"failedHop", pingResult.FailedHop,
+		)
+		if !pingResult.Status {
+			probeErr = &ProbeError{Reason: pingResult.Reason}
+		}
+
+		if reportErr := sink.Report(context.Background(), pingResult); reportErr != nil {
+			return &ReportError{Err: reportErr}
+		}
+	}
+
+	l.Info("completed webpinger")
+
+	// in k8s outputs a failed probe is a result reported like any other one
+	if c.Output == StdoutOutput || c.Output == JSONOutput {
+		return probeErr
+	}
+	return nil
 }
@@ -0,0 +1,153 @@
+package probe
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	StatusAssertion       string = "status"
+	BodyContainsAssertion string = "body-contains"
+	HeaderAssertion       string = "header"
+	MaxLatencyAssertion   string = "max-latency"
+	JSONAssertion         string = "json"
+)
+
+// Assertion checks a single property of a ping response. It is written as
+// kind=argument, for example:
+//
+//	status=200, status=2xx, body-contains=amount, header=Content-Type:application/json,
+//	max-latency=500ms, json=data.amount, json=data.base:BTC
+type Assertion struct {
+	Kind     string
+	Argument string
+}
+
+// Response is what assertions are checked against.
+type Response struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+	Latency    time.Duration
+}
+
+// DefaultAssertions are checked when an HTTP Request has no assertions.
+// Other probes only check their protocol by default.
+var DefaultAssertions = []Assertion{{Kind: StatusAssertion, Argument: "2xx"}}
+
+func (a Assertion) String() string {
+	return a.Kind + "=" + a.Argument
+}
+
+// ParseAssertion parses an assertion from its kind=argument form.
+func ParseAssertion(value string) (Assertion, error) {
+	kind, argument, found := cut(value, "=")
+	if !found || argument == "" {
+		return Assertion{}, fmt.Errorf("assertion %q must be in kind=argument form", value)
+	}
+	a := Assertion{Kind: kind, Argument: argument}
+	switch kind {
+	case StatusAssertion:
+		if _, err := strconv.Atoi(argument); err != nil && !isStatusClass(argument) {
+			return a, fmt.Errorf("assertion %q: status must be a code or a class like 2xx", value)
+		}
+	case MaxLatencyAssertion:
+		if _, err := time.ParseDuration(argument); err != nil {
+			return a, fmt.Errorf("assertion %q: %w", value, err)
+		}
+	case BodyContainsAssertion, HeaderAssertion, JSONAssertion:
+	default:
+		return a, fmt.Errorf("assertion %q: unknown kind %q", value, kind)
+	}
+	return a, nil
+}
+
+func isStatusClass(value string) bool {
+	return len(value) == 3 &&
+		value[0] >= '1' && value[0] <= '5' &&
+		strings.ToLower(value[1:]) == "xx"
+}
+
+// Check returns a description of the failure or an empty string when the
+// response satisfies the assertion.
+func (a Assertion) Check(r Response) string {
+	switch a.Kind {
+	case StatusAssertion:
+		if isStatusClass(a.Argument) {
+			if strconv.Itoa(r.StatusCode)[0] == a.Argument[0] {
+				return ""
+			}
+		} else if strconv.Itoa(r.StatusCode) == a.Argument {
+			return ""
+		}
+		return fmt.Sprintf("%s: got status %d", a, r.StatusCode)
+	case BodyContainsAssertion:
+		if strings.Contains(string(r.Body), a.Argument) {
+			return ""
+		}
+		return fmt.Sprintf("%s: body does not contain %q", a, a.Argument)
+	case HeaderAssertion:
+		name, value, hasValue := cut(a.Argument, ":")
+		got, present := r.Header[http.CanonicalHeaderKey(name)]
+		if !present {
+			return fmt.Sprintf("%s: header %s is missing", a, name)
+		}
+		if hasValue && !containsString(got, value) {
+			return fmt.Sprintf("%s: got header %s %q", a, name, got)
+		}
+		return ""
+	case MaxLatencyAssertion:
+		limit, _ := time.ParseDuration(a.Argument)
+		if r.Latency <= limit {
+			return ""
+		}
+		return fmt.Sprintf("%s: got latency %s", a, r.Latency)
+	case JSONAssertion:
+		return a.checkJSON(r.Body)
+	}
+	return fmt.Sprintf("%s: unknown assertion", a)
+}
+
+func (a Assertion) checkJSON(body []byte) string {
+	path, want, hasValue := cut(a.Argument, ":")
+	var document interface{}
+	if err := json.Unmarshal(body, &document); err != nil {
+		return fmt.Sprintf("%s: body is not JSON: %v", a, err)
+	}
+	node := document
+	for _, key := range strings.Split(path, ".") {
+		object, ok := node.(map[string]interface{})
+		if !ok {
+			return fmt.Sprintf("%s: %s is not an object", a, key)
+		}
+		if node, ok = object[key]; !ok {
+			return fmt.Sprintf("%s: field %s is missing", a, path)
+		}
+	}
+	if hasValue && fmt.Sprint(node) != want {
+		return fmt.Sprintf("%s: got %v", a, node)
+	}
+	return ""
+}
+
+// cut slices s around the first separator. It stands in for strings.Cut,
+// which the Go version of this module does not have yet.
+func cut(s, sep string) (before, after string, found bool) {
+	if i := strings.Index(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+	return s, "", false
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
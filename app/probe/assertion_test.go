@@ -0,0 +1,81 @@
+package probe
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestParseAssertion(t *testing.T) {
+	valid := []string{
+		"status=200",
+		"status=4xx",
+		"body-contains=amount",
+		"header=Content-Type:application/json",
+		"max-latency=500ms",
+		"json=data.base:BTC",
+	}
+	for _, value := range valid {
+		if _, err := ParseAssertion(value); err != nil {
+			t.Errorf("ParseAssertion(%q) failed: %v", value, err)
+		}
+	}
+
+	invalid := []string{
+		"status",
+		"status=",
+		"status=ok",
+		"max-latency=soon",
+		"unknown=1",
+	}
+	for _, value := range invalid {
+		if _, err := ParseAssertion(value); err == nil {
+			t.Errorf("ParseAssertion(%q) succeeded, want error", value)
+		}
+	}
+}
+
+func TestAssertion_Check(t *testing.T) {
+	response := Response{
+		StatusCode: 200,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       []byte(`{"data":{"base":"BTC","amount":"42000.01"}}`),
+		Latency:    100 * time.Millisecond,
+	}
+
+	tests := []struct {
+		assertion string
+		wantPass  bool
+	}{
+		{"status=200", true},
+		{"status=2xx", true},
+		{"status=201", false},
+		{"status=5xx", false},
+		{"body-contains=amount", true},
+		{"body-contains=currency", false},
+		{"header=Content-Type", true},
+		{"header=Content-Type:application/json", true},
+		{"header=Content-Type:text/plain", false},
+		{"header=Etag", false},
+		{"max-latency=1s", true},
+		{"max-latency=10ms", false},
+		{"json=data.amount", true},
+		{"json=data.base:BTC", true},
+		{"json=data.base:ETH", false},
+		{"json=data.currency", false},
+		{"json=data.base.code", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.assertion, func(t *testing.T) {
+			a, err := ParseAssertion(tt.assertion)
+			if err != nil {
+				t.Fatal(err)
+			}
+			failure := a.Check(response)
+			if (failure == "") != tt.wantPass {
+				t.Errorf("Check() = %q, want pass %v", failure, tt.wantPass)
+			}
+		})
+	}
+}
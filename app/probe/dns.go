@@ -0,0 +1,105 @@
+package probe
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// DNSRecordTypes are the record types DNSProber looks up.
+var DNSRecordTypes = []string{"A", "AAAA", "CNAME", "MX", "NS", "TXT"}
+
+// DNSProber checks that a name resolves. The answers are the response body
+// assertions are checked against, one per line.
+type DNSProber struct {
+	// RecordType is one of DNSRecordTypes, A when it is empty.
+	RecordType string
+	Resolver   *net.Resolver
+	// Now returns the ping time, time.Now is used when it is nil.
+	Now func() time.Time
+}
+
+// NewDNSProber returns a DNSProber asking server, a host or host:port, or
+// the system resolver when server is empty.
+func NewDNSProber(recordType string, server string) (*DNSProber, error) {
+	prober := &DNSProber{RecordType: recordType, Resolver: net.DefaultResolver}
+	if server == "" {
+		return prober, nil
+	}
+	addr, err := address(server, "53")
+	if err != nil {
+		return nil, err
+	}
+	prober.Resolver = &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, network, addr)
+		},
+	}
+	return prober, nil
+}
+
+// Probe looks the name up.
+func (p *DNSProber) Probe(ctx context.Context, request Request) (Result, error) {
+	result := newResult(pingTime(p.Now))
+	ctx, cancel := withTimeout(ctx, request.Timeout)
+	defer cancel()
+
+	name := request.URL
+	if parsed, err := url.Parse(name); err == nil && parsed.Host != "" {
+		name = parsed.Hostname()
+	}
+	resolver := p.Resolver
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+	start := time.Now()
+	answers, err := lookup(ctx, resolver, p.RecordType, name)
+	latency := time.Since(start)
+	result.LatencyMs = latency.Milliseconds()
+	if err != nil {
+		return result, err
+	}
+
+	result.online(strings.Join(answers, "\n"))
+	result.assert(request.Assertions, Response{Body: []byte(result.Message), Latency: latency})
+	return result, nil
+}
+
+func lookup(ctx context.Context, resolver *net.Resolver, recordType string, name string) ([]string, error) {
+	var answers []string
+	switch strings.ToUpper(recordType) {
+	case "", "A", "AAAA":
+		network := "ip4"
+		if strings.EqualFold(recordType, "AAAA") {
+			network = "ip6"
+		}
+		ips, err := resolver.LookupIP(ctx, network, name)
+		for _, ip := range ips {
+			answers = append(answers, ip.String())
+		}
+		return answers, err
+	case "CNAME":
+		cname, err := resolver.LookupCNAME(ctx, name)
+		return []string{cname}, err
+	case "MX":
+		records, err := resolver.LookupMX(ctx, name)
+		for _, mx := range records {
+			answers = append(answers, fmt.Sprintf("%d %s", mx.Pref, mx.Host))
+		}
+		return answers, err
+	case "NS":
+		records, err := resolver.LookupNS(ctx, name)
+		for _, ns := range records {
+			answers = append(answers, ns.Host)
+		}
+		return answers, err
+	case "TXT":
+		return resolver.LookupTXT(ctx, name)
+	}
+	return nil, fmt.Errorf("unsupported DNS record type %q", recordType)
+}
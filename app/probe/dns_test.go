@@ -0,0 +1,27 @@
+package probe
+
+import (
+	"context"
+	"testing"
+)
+
+func TestDNSProber_Probe(t *testing.T) {
+	prober, err := NewDNSProber("A", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	request := Request{
+		URL:        "localhost",
+		Assertions: []Assertion{{Kind: BodyContainsAssertion, Argument: "127.0.0.1"}},
+	}
+	result, err := prober.Probe(context.Background(), request)
+	if err != nil || !result.Status || result.Message != "127.0.0.1" {
+		t.Errorf("Got (%v, %q) and error [%v], want localhost resolved", result.Status, result.Message, err)
+	}
+
+	prober.RecordType = "SOA"
+	result, err = prober.Probe(context.Background(), request)
+	if err == nil || result.Type != ServiceOffline {
+		t.Errorf("Got %s and error [%v], want an unsupported record type to fail", result.Type, err)
+	}
+}
@@ -0,0 +1,77 @@
+package probe
+
+import (
+	"context"
+	"crypto/tls"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+// GRPCHealthProber calls the standard gRPC health checking service.
+type GRPCHealthProber struct {
+	// Service is checked, the server as a whole when it is empty.
+	Service string
+	// TLS connects with TLS instead of plaintext.
+	TLS                bool
+	InsecureSkipVerify bool
+	// Now returns the ping time, time.Now is used when it is nil.
+	Now func() time.Time
+}
+
+// Probe checks the health of the service at the host:port target. Servers
+// which do not serve it are online, with a failed assertion.
+func (p *GRPCHealthProber) Probe(ctx context.Context, request Request) (Result, error) {
+	result := newResult(pingTime(p.Now))
+	ctx, cancel := withTimeout(ctx, request.Timeout)
+	defer cancel()
+
+	defaultPort := ""
+	if p.TLS {
+		defaultPort = "443"
+	}
+	addr, err := address(request.URL, defaultPort)
+	if err != nil {
+		return result, err
+	}
+	creds := insecure.NewCredentials()
+	if p.TLS {
+		creds = credentials.NewTLS(&tls.Config{InsecureSkipVerify: p.InsecureSkipVerify})
+	}
+
+	start := time.Now()
+	conn, err := grpc.DialContext(
+		ctx,
+		addr,
+		grpc.WithTransportCredentials(creds),
+		grpc.WithBlock(),
+		grpc.FailOnNonTempDialError(true),
+	)
+	if err != nil {
+		result.LatencyMs = time.Since(start).Milliseconds()
+		return result, err
+	}
+	defer conn.Close()
+	response, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{Service: p.Service})
+	latency := time.Since(start)
+	result.LatencyMs = latency.Milliseconds()
+	switch code := status.Code(err); {
+	case err == nil:
+		result.online(response.Status.String())
+		if response.Status != healthpb.HealthCheckResponse_SERVING {
+			result.fail("grpc-status=SERVING: got %s", response.Status)
+		}
+	case code == codes.NotFound || code == codes.Unimplemented:
+		result.online(err.Error())
+		result.fail("grpc-status=SERVING: %s", status.Convert(err).Message())
+	default:
+		return result, err
+	}
+	result.assert(request.Assertions, Response{Body: []byte(result.Message), Latency: latency})
+	return result, nil
+}
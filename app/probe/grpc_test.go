@@ -0,0 +1,55 @@
+package probe
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func TestGRPCHealthProber_Probe(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus("prices", healthpb.HealthCheckResponse_SERVING)
+	healthServer.SetServingStatus("orders", healthpb.HealthCheckResponse_NOT_SERVING)
+	server := grpc.NewServer()
+	healthpb.RegisterHealthServer(server, healthServer)
+	go server.Serve(listener)
+	defer server.Stop()
+
+	tests := []struct {
+		service    string
+		wantStatus bool
+		wantFailed string
+	}{
+		{service: "", wantStatus: true},
+		{service: "prices", wantStatus: true},
+		{service: "orders", wantFailed: "grpc-status=SERVING: got NOT_SERVING"},
+		{service: "unknown", wantFailed: "grpc-status=SERVING: unknown service"},
+	}
+	for _, tt := range tests {
+		prober := &GRPCHealthProber{Service: tt.service}
+		request := Request{URL: listener.Addr().String(), Timeout: 5 * time.Second}
+		result, err := prober.Probe(context.Background(), request)
+		if err != nil || result.Type != ServiceOnline || result.Status != tt.wantStatus {
+			t.Errorf("Got (%s, %v) and error [%v] for %q, want online", result.Type, result.Status, err, tt.service)
+		}
+		if tt.wantFailed != "" && (len(result.FailedAssertions) != 1 || result.FailedAssertions[0] != tt.wantFailed) {
+			t.Errorf("Got failed assertions %v for %q, want [%s]", result.FailedAssertions, tt.service, tt.wantFailed)
+		}
+	}
+
+	server.Stop()
+	prober := &GRPCHealthProber{}
+	result, err := prober.Probe(context.Background(), Request{URL: listener.Addr().String(), Timeout: time.Second})
+	if err == nil || result.Type != ServiceOffline {
+		t.Errorf("Got %s and error [%v], want the stopped server offline", result.Type, err)
+	}
+}
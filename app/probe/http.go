@@ -0,0 +1,171 @@
+package probe
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"time"
+)
+
+// HTTPProber probes HTTP endpoints.
+type HTTPProber struct {
+	Client *http.Client
+	// Now returns the ping time, time.Now is used when it is nil.
+	Now func() time.Time
+}
+
+// NewHTTPProber returns an HTTPProber with its own transport. Requests go
+// through the proxy returned by proxy, http.ProxyFromEnvironment is used
+// when it is nil.
+func NewHTTPProber(insecureSkipVerify bool, proxy func(*http.Request) (*url.URL, error)) *HTTPProber {
+	if proxy == nil {
+		proxy = http.ProxyFromEnvironment
+	}
+	return &HTTPProber{
+		Client: &http.Client{
+			Transport: &http.Transport{
+				Proxy:           proxy,
+				TLSClientConfig: &tls.Config{InsecureSkipVerify: insecureSkipVerify},
+			},
+		},
+	}
+}
+
+// ProxyWithCredentials adds the credentials to proxy URLs returned by proxy
+// which have none, so they need not be part of HTTPS_PROXY.
+func ProxyWithCredentials(
+	proxy func(*http.Request) (*url.URL, error),
+	username string,
+	password string,
+) func(*http.Request) (*url.URL, error) {
+	return func(request *http.Request) (*url.URL, error) {
+		proxyURL, err := proxy(request)
+		if err != nil || proxyURL == nil || proxyURL.User != nil || username == "" {
+			return proxyURL, err
+		}
+		withCredentials := *proxyURL
+		withCredentials.User = url.UserPassword(username, password)
+		return &withCredentials, nil
+	}
+}
+
+// proxyURL returns the proxy the transport sends the request through.
+func (p *HTTPProber) proxyURL(request *http.Request) *url.URL {
+	transport, ok := p.Client.Transport.(*http.Transport)
+	if !ok || transport.Proxy == nil {
+		return nil
+	}
+	proxyURL, err := transport.Proxy(request)
+	if err != nil {
+		return nil
+	}
+	return proxyURL
+}
+
+// Probe sends the request and checks the response against its assertions.
+func (p *HTTPProber) Probe(ctx context.Context, request Request) (result Result, err error) {
+	result = newResult(pingTime(p.Now))
+
+	ctx, cancel := withTimeout(ctx, request.Timeout)
+	defer cancel()
+
+	method := request.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+	httpRequest, err := http.NewRequestWithContext(ctx, method, request.URL, nil)
+	if err != nil {
+		return result, err
+	}
+
+	proxyURL := p.proxyURL(httpRequest)
+	tunnel := &proxyTunnel{proxyURL: proxyURL}
+	if proxyURL != nil {
+		result.Proxy = proxyURL.Redacted()
+		httpRequest = httpRequest.WithContext(httptrace.WithClientTrace(ctx, tunnel.trace()))
+	}
+	defer func() {
+		if !result.Status {
+			result.markFailedHop(proxyURL != nil && !tunnel.established)
+		}
+	}()
+
+	start := time.Now()
+	response, err := p.Client.Do(httpRequest)
+	latency := time.Since(start)
+	result.LatencyMs = latency.Milliseconds()
+
+	if err != nil {
+		return result, err
+	}
+
+	defer response.Body.Close()
+
+	result.Type = ServiceOnline
+	result.StatusCode = response.StatusCode
+	result.Header = response.Header
+
+	body, err := io.ReadAll(response.Body)
+	result.Message = string(body)
+	if err != nil {
+		return result, err
+	}
+
+	if proxyURL != nil && response.StatusCode == http.StatusProxyAuthRequired {
+		// the proxy answered for the target, which was not reached
+		tunnel.established = false
+		return result, nil
+	}
+
+	assertions := request.Assertions
+	if len(assertions) == 0 {
+		assertions = DefaultAssertions
+	}
+	result.assert(assertions, Response{
+		StatusCode: response.StatusCode,
+		Header:     response.Header,
+		Body:       body,
+		Latency:    latency,
+	})
+
+	return result, nil
+}
+
+// proxyTunnel follows a request through the proxy. The tunnel counts as
+// established once the target is talked to: on the TLS handshake with it
+// after CONNECT, or on the connection to the proxy for plain HTTP targets.
+type proxyTunnel struct {
+	proxyURL    *url.URL
+	handshakes  int
+	established bool
+}
+
+func (t *proxyTunnel) trace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		TLSHandshakeStart: func() {
+			t.handshakes++
+			// the first handshake of an HTTPS proxy is the one with the proxy
+			if t.proxyURL.Scheme != "https" || t.handshakes > 1 {
+				t.established = true
+			}
+		},
+		GotConn: func(httptrace.GotConnInfo) {
+			t.established = true
+		},
+	}
+}
+
+// markFailedHop records which hop failed the probe. Probes failed by the
+// proxy tell nothing about the target, so their type is ServiceUnknown.
+func (result *Result) markFailedHop(proxyFailed bool) {
+	if !proxyFailed {
+		result.FailedHop = TargetHop
+		return
+	}
+	result.FailedHop = ProxyHop
+	result.Type = ServiceUnknown
+	result.Reason = ProxyFailed
+}
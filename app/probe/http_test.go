@@ -0,0 +1,189 @@
+package probe
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHTTPProber_Probe(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/prices/BTC-USD/buy":
+			fmt.Fprint(w, `{"data":{"base":"BTC","currency":"USD","amount":"42000.01"}}`)
+		case "/slow":
+			time.Sleep(200 * time.Millisecond)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	pingTime := time.Date(2021, 9, 1, 12, 0, 0, 0, time.UTC)
+	prober := NewHTTPProber(false, nil)
+	prober.Now = func() time.Time { return pingTime }
+
+	tests := []struct {
+		name        string
+		request     Request
+		wantType    string
+		wantStatus  bool
+		wantReason  string
+		wantFailed  int
+		wantProbErr bool
+	}{
+		{
+			name:       "succeeded",
+			request:    Request{URL: server.URL + "/prices/BTC-USD/buy"},
+			wantType:   ServiceOnline,
+			wantStatus: true,
+			wantReason: PingSucceeded,
+		},
+		{
+			name:       "default assertion fails on not found",
+			request:    Request{URL: server.URL + "/missing"},
+			wantType:   ServiceOnline,
+			wantReason: AssertionFailed,
+			wantFailed: 1,
+		},
+		{
+			name: "custom assertions",
+			request: Request{
+				URL: server.URL + "/prices/BTC-USD/buy",
+				Assertions: []Assertion{
+					{Kind: JSONAssertion, Argument: "data.base:BTC"},
+					{Kind: JSONAssertion, Argument: "data.base:ETH"},
+				},
+			},
+			wantType:   ServiceOnline,
+			wantReason: AssertionFailed,
+			wantFailed: 1,
+		},
+		{
+			name:        "timeout",
+			request:     Request{URL: server.URL + "/slow", Timeout: 50 * time.Millisecond},
+			wantType:    ServiceOffline,
+			wantReason:  PingFailed,
+			wantProbErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := prober.Probe(context.Background(), tt.request)
+			if (err != nil) != tt.wantProbErr {
+				t.Errorf("Probe() error = %v, want error %v", err, tt.wantProbErr)
+			}
+			if result.Type != tt.wantType || result.Status != tt.wantStatus || result.Reason != tt.wantReason {
+				t.Errorf(
+					"Got (%s, %v, %s), want (%s, %v, %s)",
+					result.Type, result.Status, result.Reason,
+					tt.wantType, tt.wantStatus, tt.wantReason,
+				)
+			}
+			if len(result.FailedAssertions) != tt.wantFailed {
+				t.Errorf("Got failed assertions %v, want %d", result.FailedAssertions, tt.wantFailed)
+			}
+			if result.PingTime != "2021-09-01T12:00:00Z" {
+				t.Errorf("Got ping time [%s]", result.PingTime)
+			}
+		})
+	}
+}
+
+func TestHTTPProber_Probe_proxy(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/down" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer target.Close()
+	forward := &httputil.ReverseProxy{Director: func(*http.Request) {}}
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodConnect:
+			http.Error(w, "tunnels are not allowed", http.StatusForbidden)
+		case r.Header.Get("Proxy-Authorization") == "":
+			w.WriteHeader(http.StatusProxyAuthRequired)
+		default:
+			forward.ServeHTTP(w, r)
+		}
+	}))
+	defer proxy.Close()
+	proxyURL, _ := url.Parse(proxy.URL)
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closedURL, _ := url.Parse(closed.URL)
+	closed.Close()
+
+	tests := []struct {
+		name       string
+		proxy      func(*http.Request) (*url.URL, error)
+		url        string
+		wantType   string
+		wantReason string
+		wantHop    string
+	}{
+		{
+			name:       "through the proxy",
+			proxy:      ProxyWithCredentials(http.ProxyURL(proxyURL), "user", "secret"),
+			url:        target.URL,
+			wantType:   ServiceOnline,
+			wantReason: PingSucceeded,
+		},
+		{
+			name:       "target failed",
+			proxy:      ProxyWithCredentials(http.ProxyURL(proxyURL), "user", "secret"),
+			url:        target.URL + "/down",
+			wantType:   ServiceOnline,
+			wantReason: AssertionFailed,
+			wantHop:    TargetHop,
+		},
+		{
+			name:       "proxy authentication required",
+			proxy:      http.ProxyURL(proxyURL),
+			url:        target.URL,
+			wantType:   ServiceUnknown,
+			wantReason: ProxyFailed,
+			wantHop:    ProxyHop,
+		},
+		{
+			name:       "tunnel refused",
+			proxy:      http.ProxyURL(proxyURL),
+			url:        "https://api.example.org/v2",
+			wantType:   ServiceUnknown,
+			wantReason: ProxyFailed,
+			wantHop:    ProxyHop,
+		},
+		{
+			name:       "proxy down",
+			proxy:      http.ProxyURL(closedURL),
+			url:        target.URL,
+			wantType:   ServiceUnknown,
+			wantReason: ProxyFailed,
+			wantHop:    ProxyHop,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prober := NewHTTPProber(false, tt.proxy)
+			result, _ := prober.Probe(context.Background(), Request{URL: tt.url, Timeout: time.Second})
+			if result.Type != tt.wantType || result.Reason != tt.wantReason || result.FailedHop != tt.wantHop {
+				t.Errorf(
+					"Got (%s, %s, %q), want (%s, %s, %q)",
+					result.Type, result.Reason, result.FailedHop,
+					tt.wantType, tt.wantReason, tt.wantHop,
+				)
+			}
+			if result.Proxy == "" || strings.Contains(result.Proxy, "secret") {
+				t.Errorf("Got proxy [%s], want it without the password", result.Proxy)
+			}
+		})
+	}
+}
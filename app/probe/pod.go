@@ -0,0 +1,65 @@
+package probe
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	TypeLabel                  string = "type"
+	StatusLabel                string = "status"
+	ReasonLabel                string = "reason"
+	MessageAnnotation          string = "message"
+	PingTimeAnnotation         string = "ping-time"
+	FailedAssertionsAnnotation string = "failed-assertions"
+	FailedHopAnnotation        string = "failed-hop"
+)
+
+// PodSink writes Results to labels and annotations of a pod, where the
+// operator collects them from.
+type PodSink struct {
+	Clientset kubernetes.Interface
+	Namespace string
+	Name      string
+}
+
+// Report merge patches the result into the labels and annotations of the
+// pod. Patching needs no read of the pod and does not conflict with
+// concurrent changes, so the pod only needs to be allowed to patch pods.
+func (s PodSink) Report(ctx context.Context, result Result) error {
+	patch, err := resultPatch(result)
+	if err != nil {
+		return err
+	}
+	_, err = s.Clientset.CoreV1().Pods(s.Namespace).Patch(ctx, s.Name, types.MergePatchType, patch, metav1.PatchOptions{})
+	return err
+}
+
+func resultPatch(updateData Result) ([]byte, error) {
+	annotations := map[string]string{
+		MessageAnnotation: updateData.Message,
+		// ping-time annotation holds the JSON form of metav1.Time
+		PingTimeAnnotation: strconv.Quote(updateData.PingTime),
+	}
+	if len(updateData.FailedAssertions) > 0 {
+		annotations[FailedAssertionsAnnotation] = strings.Join(updateData.FailedAssertions, "\n")
+	}
+	if updateData.FailedHop != "" {
+		annotations[FailedHopAnnotation] = updateData.FailedHop
+	}
+	labels := map[string]string{
+		TypeLabel:   updateData.Type,
+		StatusLabel: fmt.Sprintf("%v", updateData.Status),
+		ReasonLabel: updateData.Reason,
+	}
+	return json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{"labels": labels, "annotations": annotations},
+	})
+}
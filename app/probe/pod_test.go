@@ -0,0 +1,90 @@
+package probe
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestPodSink_Report(t *testing.T) {
+	clientset := fake.NewSimpleClientset(&v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "pinger",
+			Namespace: "default",
+			Labels:    map[string]string{"webapp-pinger": "uid"},
+		},
+	})
+	sink := PodSink{Clientset: clientset, Namespace: "default", Name: "pinger"}
+
+	err := sink.Report(context.Background(), Result{
+		Type:             ServiceOnline,
+		Reason:           AssertionFailed,
+		Message:          "{}",
+		PingTime:         "2021-09-01T12:00:00Z",
+		FailedAssertions: []string{"first", "second"},
+		FailedHop:        TargetHop,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pod, err := clientset.CoreV1().Pods("default").Get(context.Background(), "pinger", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantLabels := map[string]string{
+		"webapp-pinger": "uid",
+		TypeLabel:       ServiceOnline,
+		StatusLabel:     "false",
+		ReasonLabel:     AssertionFailed,
+	}
+	for k, v := range wantLabels {
+		if pod.Labels[k] != v {
+			t.Errorf("Got label %s=[%s], want [%s]", k, pod.Labels[k], v)
+		}
+	}
+
+	pingTime := metav1.Time{}
+	if err := pingTime.UnmarshalJSON([]byte(pod.Annotations[PingTimeAnnotation])); err != nil {
+		t.Errorf("ping-time annotation is not a metav1.Time: %v", err)
+	}
+	if got := pod.Annotations[FailedAssertionsAnnotation]; got != "first\nsecond" {
+		t.Errorf("Got failed-assertions annotation [%s]", got)
+	}
+	if got := pod.Annotations[FailedHopAnnotation]; got != TargetHop {
+		t.Errorf("Got failed-hop annotation [%s]", got)
+	}
+}
+
+func TestPodSink_Report_onlyPatches(t *testing.T) {
+	clientset := fake.NewSimpleClientset(&v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "pinger", Namespace: "default"},
+	})
+	// the pinger Role grants nothing but patch pods
+	clientset.PrependReactor("*", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.GetVerb() == "patch" {
+			return false, nil, nil
+		}
+		return true, nil, apierrors.NewForbidden(v1.Resource("pods"), "pinger", errors.New(action.GetVerb()))
+	})
+	sink := PodSink{Clientset: clientset, Namespace: "default", Name: "pinger"}
+
+	err := sink.Report(context.Background(), Result{Type: ServiceOnline, PingTime: "2021-09-01T12:00:00Z"})
+	if err != nil {
+		t.Fatalf("Report() failed with patch only: %v", err)
+	}
+	pod, err := clientset.Tracker().Get(v1.SchemeGroupVersion.WithResource("pods"), "default", "pinger")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if labels := pod.(*v1.Pod).Labels; labels[TypeLabel] != ServiceOnline {
+		t.Errorf("Got label %s=[%s], want [%s]", TypeLabel, labels[TypeLabel], ServiceOnline)
+	}
+}
@@ -0,0 +1,161 @@
+// Package probe pings a target, checks the response against assertions and
+// reports the Result to pluggable sinks.
+package probe
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	ServiceOffline string = "ServiceOffline"
+	ServiceOnline  string = "ServiceOnline"
+	// ServiceUnknown is the type of probes which did not reach the target,
+	// because the proxy in between failed.
+	ServiceUnknown string = "ServiceUnknown"
+
+	PingSucceeded   string = "PingSucceeded"
+	PingFailed      string = "PingFailed"
+	AssertionFailed string = "AssertionFailed"
+	ProxyFailed     string = "ProxyFailed"
+
+	// FailedHop values of failed probes.
+	ProxyHop  string = "proxy"
+	TargetHop string = "target"
+)
+
+// Probe types, each implemented by its own Prober.
+const (
+	HTTPProbe string = "http"
+	TCPProbe  string = "tcp"
+	DNSProbe  string = "dns"
+	TLSProbe  string = "tls"
+	GRPCProbe string = "grpc"
+	// WebSocketProbe subscribes to a feed, see WebSocketProber.
+	WebSocketProbe string = "websocket"
+)
+
+// Request describes a single probe. URL is the target of probes other than
+// HTTP too: host:port or a URL for TCP, TLS and gRPC, a name for DNS and a
+// ws or wss URL for WebSocket feeds.
+type Request struct {
+	Method     string
+	URL        string
+	Timeout    time.Duration
+	Assertions []Assertion
+}
+
+// Result is the outcome of a probe.
+type Result struct {
+	Type             string   `json:"type"`
+	Status           bool     `json:"status"`
+	Reason           string   `json:"reason"`
+	Message          string   `json:"message"`
+	PingTime         string   `json:"pingTime"`
+	StatusCode       int      `json:"statusCode,omitempty"`
+	LatencyMs        int64    `json:"latencyMs"`
+	FailedAssertions []string `json:"failedAssertions,omitempty"`
+	// Proxy is the proxy the probe went through, without credentials.
+	Proxy string `json:"proxy,omitempty"`
+	// FailedHop tells whether the proxy or the target failed the probe.
+	FailedHop string `json:"failedHop,omitempty"`
+	// ConnectMs, FirstMessageMs and StalenessMs measure WebSocket feeds:
+	// the time to connect, from subscribing to the first message and the
+	// largest age of a message against its server timestamp.
+	ConnectMs      int64 `json:"connectMs,omitempty"`
+	FirstMessageMs int64 `json:"firstMessageMs,omitempty"`
+	StalenessMs    int64 `json:"stalenessMs,omitempty"`
+
+	// Header holds response headers. It is not reported by sinks.
+	Header http.Header `json:"-"`
+}
+
+// Prober runs probes. Transport failures are reported in the Result as well
+// as returned, so the Result is always usable.
+type Prober interface {
+	Probe(ctx context.Context, request Request) (Result, error)
+}
+
+// newResult returns the Result of a probe which did not reach the target.
+func newResult(now time.Time) Result {
+	return Result{
+		Type:     ServiceOffline,
+		Status:   false,
+		Reason:   PingFailed,
+		Message:  "",
+		PingTime: now.UTC().Format(time.RFC3339),
+	}
+}
+
+// pingTime returns the time of a probe, now returns it when it is set.
+func pingTime(now func() time.Time) time.Time {
+	if now == nil {
+		return time.Now()
+	}
+	return now()
+}
+
+// withTimeout bounds ctx by the timeout of a Request, if it has one.
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout > 0 {
+		return context.WithTimeout(ctx, timeout)
+	}
+	return context.WithCancel(ctx)
+}
+
+// address returns the host:port of a target given as host:port or as a
+// URL, with defaultPort when it has no port.
+func address(target string, defaultPort string) (string, error) {
+	host := target
+	if strings.Contains(target, "://") {
+		parsed, err := url.Parse(target)
+		if err != nil {
+			return "", err
+		}
+		host = parsed.Host
+	}
+	if host == "" {
+		return "", fmt.Errorf("target %q has no host", target)
+	}
+	if _, _, err := net.SplitHostPort(host); err == nil {
+		return host, nil
+	}
+	if defaultPort == "" {
+		return "", fmt.Errorf("target %q has no port", target)
+	}
+	return net.JoinHostPort(strings.Trim(host, "[]"), defaultPort), nil
+}
+
+// online marks the Result of a probe which reached the target.
+func (result *Result) online(message string) {
+	result.Type = ServiceOnline
+	result.Message = message
+}
+
+// fail records a failed protocol check, like an expiring certificate, as
+// a failed assertion.
+func (result *Result) fail(format string, args ...interface{}) {
+	result.FailedAssertions = append(result.FailedAssertions, fmt.Sprintf(format, args...))
+}
+
+// assert checks the response and marks the Result succeeded when every
+// assertion holds and no protocol check failed before.
+func (result *Result) assert(assertions []Assertion, response Response) {
+	for _, a := range assertions {
+		if failure := a.Check(response); failure != "" {
+			result.FailedAssertions = append(result.FailedAssertions, failure)
+		}
+	}
+
+	if len(result.FailedAssertions) == 0 {
+		result.Status = true
+		result.Reason = PingSucceeded
+	} else {
+		result.Reason = AssertionFailed
+	}
+}
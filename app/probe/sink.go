@@ -0,0 +1,59 @@
+package probe
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// ResultSink delivers probe Results somewhere.
+type ResultSink interface {
+	Report(ctx context.Context, result Result) error
+}
+
+// MultiSink reports to every sink and returns the first error.
+type MultiSink []ResultSink
+
+func (sinks MultiSink) Report(ctx context.Context, result Result) error {
+	var firstErr error
+	for _, sink := range sinks {
+		if err := sink.Report(ctx, result); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// WriterSink writes Results to a writer such as stdout, one per line, as
+// text or as JSON.
+type WriterSink struct {
+	Writer io.Writer
+	JSON   bool
+}
+
+func (s WriterSink) Report(_ context.Context, result Result) error {
+	if s.JSON {
+		return json.NewEncoder(s.Writer).Encode(result)
+	}
+
+	_, err := fmt.Fprintf(
+		s.Writer,
+		"%s %s %s status=%d latency=%dms\n",
+		result.PingTime,
+		result.Type,
+		result.Reason,
+		result.StatusCode,
+		result.LatencyMs,
+	)
+	if err == nil && result.FailedHop != "" {
+		_, err = fmt.Fprintf(s.Writer, "  failed hop %s %s\n", result.FailedHop, result.Proxy)
+	}
+	for _, failure := range result.FailedAssertions {
+		if err != nil {
+			break
+		}
+		_, err = fmt.Fprintf(s.Writer, "  failed %s\n", failure)
+	}
+	return err
+}
@@ -0,0 +1,87 @@
+package probe
+
+import (
+	"context"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/util/retry"
+)
+
+// CoinbasePingerResource is the resource StatusSink writes to.
+var CoinbasePingerResource = schema.GroupVersionResource{
+	Group:    "batch.dev.org",
+	Version:  "v1",
+	Resource: "coinbasepingers",
+}
+
+// maxStatusHistory is the number of results StatusSink keeps in the status.
+// It matches the history the operator keeps, see its mergeConditions.
+const maxStatusHistory = 20
+
+// StatusSink appends Results to the status conditions of a CoinbasePinger,
+// dropping the oldest ones beyond maxStatusHistory.
+type StatusSink struct {
+	Client    dynamic.Interface
+	Namespace string
+	Name      string
+}
+
+// Report updates the status, fetching the CoinbasePinger again when the
+// update conflicts with a concurrent change.
+func (s StatusSink) Report(ctx context.Context, result Result) error {
+	pingers := s.Client.Resource(CoinbasePingerResource).Namespace(s.Namespace)
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		pinger, err := pingers.Get(ctx, s.Name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		updatingPinger, err := pingerWithResult(pinger, result)
+		if err != nil {
+			return err
+		}
+		_, err = pingers.UpdateStatus(ctx, updatingPinger, metav1.UpdateOptions{})
+		return err
+	})
+}
+
+func pingerWithResult(
+	pinger *unstructured.Unstructured,
+	updateData Result,
+) (*unstructured.Unstructured, error) {
+	updatingPinger := pinger.DeepCopy()
+
+	condition := map[string]interface{}{
+		"type":     updateData.Type,
+		"status":   updateData.Status,
+		"reason":   updateData.Reason,
+		"message":  updateData.Message,
+		"pingTime": updateData.PingTime,
+	}
+	if len(updateData.FailedAssertions) > 0 {
+		failures := make([]interface{}, 0, len(updateData.FailedAssertions))
+		for _, failure := range updateData.FailedAssertions {
+			failures = append(failures, failure)
+		}
+		condition["failedAssertions"] = failures
+	}
+	if updateData.FailedHop != "" {
+		condition["failedHop"] = updateData.FailedHop
+	}
+
+	conditions, _, err := unstructured.NestedSlice(updatingPinger.Object, "status", "conditions")
+	if err != nil {
+		return nil, err
+	}
+	conditions = append(conditions, condition)
+	if len(conditions) > maxStatusHistory {
+		conditions = conditions[len(conditions)-maxStatusHistory:]
+	}
+	err = unstructured.SetNestedSlice(updatingPinger.Object, conditions, "status", "conditions")
+	if err != nil {
+		return nil, err
+	}
+	return updatingPinger, nil
+}
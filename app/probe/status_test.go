@@ -0,0 +1,53 @@
+package probe
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic/fake"
+)
+
+func TestStatusSink_Report_keepsHistoryBounded(t *testing.T) {
+	pinger := &unstructured.Unstructured{}
+	pinger.SetGroupVersionKind(schema.GroupVersionKind{Group: "batch.dev.org", Version: "v1", Kind: "CoinbasePinger"})
+	pinger.SetNamespace("default")
+	pinger.SetName("btc")
+	client := fake.NewSimpleDynamicClientWithCustomListKinds(
+		runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{CoinbasePingerResource: "CoinbasePingerList"},
+		pinger,
+	)
+	sink := StatusSink{Client: client, Namespace: "default", Name: "btc"}
+
+	for i := 0; i < maxStatusHistory+5; i++ {
+		err := sink.Report(context.Background(), Result{
+			Type:     ServiceOnline,
+			Status:   true,
+			PingTime: fmt.Sprintf("2021-09-01T12:%02d:00Z", i),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	stored, err := client.Resource(CoinbasePingerResource).Namespace("default").Get(context.Background(), "btc", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	conditions, _, err := unstructured.NestedSlice(stored.Object, "status", "conditions")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(conditions) != maxStatusHistory {
+		t.Fatalf("Got %d conditions, want %d", len(conditions), maxStatusHistory)
+	}
+	oldest := conditions[0].(map[string]interface{})["pingTime"]
+	if oldest != "2021-09-01T12:05:00Z" {
+		t.Errorf("Got oldest ping time [%v], want [2021-09-01T12:05:00Z]", oldest)
+	}
+}
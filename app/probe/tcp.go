@@ -0,0 +1,38 @@
+package probe
+
+import (
+	"context"
+	"net"
+	"time"
+)
+
+// TCPProber checks that a TCP port accepts connections.
+type TCPProber struct {
+	Dialer net.Dialer
+	// Now returns the ping time, time.Now is used when it is nil.
+	Now func() time.Time
+}
+
+// Probe connects to the host:port target and closes the connection again.
+func (p *TCPProber) Probe(ctx context.Context, request Request) (Result, error) {
+	result := newResult(pingTime(p.Now))
+	ctx, cancel := withTimeout(ctx, request.Timeout)
+	defer cancel()
+
+	addr, err := address(request.URL, "")
+	if err != nil {
+		return result, err
+	}
+	start := time.Now()
+	conn, err := p.Dialer.DialContext(ctx, "tcp", addr)
+	latency := time.Since(start)
+	result.LatencyMs = latency.Milliseconds()
+	if err != nil {
+		return result, err
+	}
+	conn.Close()
+
+	result.online("connected to " + conn.RemoteAddr().String())
+	result.assert(request.Assertions, Response{Body: []byte(result.Message), Latency: latency})
+	return result, nil
+}
@@ -0,0 +1,31 @@
+package probe
+
+import (
+	"context"
+	"net"
+	"testing"
+)
+
+func TestTCPProber_Probe(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := listener.Addr().String()
+	prober := &TCPProber{}
+
+	result, err := prober.Probe(context.Background(), Request{URL: "tcp://" + addr})
+	if err != nil || result.Type != ServiceOnline || !result.Status {
+		t.Errorf("Got (%s, %v) and error [%v], want the port online", result.Type, result.Status, err)
+	}
+
+	listener.Close()
+	result, err = prober.Probe(context.Background(), Request{URL: addr})
+	if err == nil || result.Type != ServiceOffline || result.Reason != PingFailed {
+		t.Errorf("Got (%s, %s) and error [%v], want the closed port offline", result.Type, result.Reason, err)
+	}
+
+	if _, err := prober.Probe(context.Background(), Request{URL: "127.0.0.1"}); err == nil {
+		t.Error("Got no error for a target without port")
+	}
+}
@@ -0,0 +1,61 @@
+package probe
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+)
+
+const (
+	// DefaultTerminationLogPath is where Kubernetes reads the container
+	// termination message from, unless the pod spec sets another path.
+	DefaultTerminationLogPath string = "/dev/termination-log"
+
+	// TerminationMessageLimit is the size Kubernetes truncates a container
+	// termination message to.
+	TerminationMessageLimit int = 4096
+)
+
+// TerminationLogSink writes the latest Result as compact JSON to the
+// container termination message file. Kubernetes keeps it in the container
+// status, so the result survives a failed pod metadata update.
+type TerminationLogSink struct {
+	Path string
+}
+
+func (s TerminationLogSink) Report(_ context.Context, result Result) error {
+	message, err := CompactJSON(result, TerminationMessageLimit)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(s.Path, message, 0644)
+}
+
+// CompactJSON encodes the Result in at most limit bytes, shortening the
+// message and then failed assertions until it fits, so the JSON is never cut
+// in the middle.
+func CompactJSON(result Result, limit int) ([]byte, error) {
+	encoded, err := json.Marshal(result)
+	if err != nil {
+		return nil, err
+	}
+	for len(encoded) > limit {
+		switch {
+		case result.Message != "":
+			overflow := len(encoded) - limit
+			keep := len(result.Message) - overflow
+			if keep < 0 {
+				keep = 0
+			}
+			result.Message = result.Message[:keep]
+		case len(result.FailedAssertions) > 0:
+			result.FailedAssertions = result.FailedAssertions[:len(result.FailedAssertions)-1]
+		default:
+			return encoded[:0], nil
+		}
+		if encoded, err = json.Marshal(result); err != nil {
+			return nil, err
+		}
+	}
+	return encoded, nil
+}
@@ -0,0 +1,39 @@
+package probe
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestCompactJSON(t *testing.T) {
+	result := Result{
+		Type:             ServiceOnline,
+		Reason:           AssertionFailed,
+		Message:          strings.Repeat(`"quoted" `, 1000),
+		PingTime:         "2021-09-01T12:00:00Z",
+		FailedAssertions: []string{"status=2xx: got status 503"},
+	}
+
+	encoded, err := CompactJSON(result, TerminationMessageLimit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(encoded) > TerminationMessageLimit {
+		t.Errorf("Got %d bytes, want at most %d", len(encoded), TerminationMessageLimit)
+	}
+
+	decoded := Result{}
+	if err := json.Unmarshal(encoded, &decoded); err != nil {
+		t.Fatalf("Compact JSON does not decode: %v", err)
+	}
+	if decoded.Reason != result.Reason || decoded.PingTime != result.PingTime {
+		t.Errorf("Got %+v, want reason and ping time of %+v", decoded, result)
+	}
+	if len(decoded.FailedAssertions) != 1 {
+		t.Errorf("Got failed assertions %v, want them kept", decoded.FailedAssertions)
+	}
+	if !strings.HasPrefix(result.Message, decoded.Message) || decoded.Message == "" {
+		t.Errorf("Got message of %d bytes, want a prefix of the original", len(decoded.Message))
+	}
+}
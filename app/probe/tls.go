@@ -0,0 +1,86 @@
+package probe
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"time"
+)
+
+// TLSProber checks the TLS handshake and the certificate of a server
+// without speaking any protocol on top. The certificate is verified even
+// when HTTP probes skip verification, that is what it probes.
+type TLSProber struct {
+	// ServerName is verified against the certificate, the target host when
+	// it is empty.
+	ServerName string
+	// MinValidity is how long the certificate must remain valid at least.
+	MinValidity time.Duration
+	// RootCAs verify the certificate, the system ones when it is nil.
+	RootCAs *x509.CertPool
+	// Now returns the ping time, time.Now is used when it is nil. The
+	// certificate is verified at the ping time.
+	Now func() time.Time
+}
+
+// Probe does the handshake with the host:port target, port 443 by default.
+// Certificates failing verification are reported as failed assertions.
+func (p *TLSProber) Probe(ctx context.Context, request Request) (Result, error) {
+	now := pingTime(p.Now)
+	result := newResult(now)
+	ctx, cancel := withTimeout(ctx, request.Timeout)
+	defer cancel()
+
+	addr, err := address(request.URL, "443")
+	if err != nil {
+		return result, err
+	}
+	serverName := p.ServerName
+	if serverName == "" {
+		serverName, _, _ = net.SplitHostPort(addr)
+	}
+	dialer := &tls.Dialer{Config: &tls.Config{
+		ServerName: serverName,
+		// verified below, so an invalid certificate is a result and not a
+		// handshake failure
+		InsecureSkipVerify: true,
+	}}
+	start := time.Now()
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	latency := time.Since(start)
+	result.LatencyMs = latency.Milliseconds()
+	if err != nil {
+		return result, err
+	}
+	state := conn.(*tls.Conn).ConnectionState()
+	conn.Close()
+
+	leaf := state.PeerCertificates[0]
+	result.online(fmt.Sprintf(
+		"%s certificate %q issued by %q valid until %s",
+		tls.CipherSuiteName(state.CipherSuite),
+		leaf.Subject.CommonName,
+		leaf.Issuer.CommonName,
+		leaf.NotAfter.UTC().Format(time.RFC3339),
+	))
+	intermediates := x509.NewCertPool()
+	for _, cert := range state.PeerCertificates[1:] {
+		intermediates.AddCert(cert)
+	}
+	_, err = leaf.Verify(x509.VerifyOptions{
+		DNSName:       serverName,
+		Roots:         p.RootCAs,
+		Intermediates: intermediates,
+		CurrentTime:   now,
+	})
+	if err != nil {
+		result.fail("tls-verify: %v", err)
+	}
+	if remaining := leaf.NotAfter.Sub(now); p.MinValidity > 0 && remaining < p.MinValidity {
+		result.fail("tls-min-validity=%s: certificate expires in %s", p.MinValidity, remaining.Round(time.Minute))
+	}
+	result.assert(request.Assertions, Response{Body: []byte(result.Message), Latency: latency})
+	return result, nil
+}
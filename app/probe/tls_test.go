@@ -0,0 +1,66 @@
+package probe
+
+import (
+	"context"
+	"crypto/x509"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTLSProber_Probe(t *testing.T) {
+	server := httptest.NewTLSServer(http.NotFoundHandler())
+	defer server.Close()
+	roots := x509.NewCertPool()
+	roots.AddCert(server.Certificate())
+	target := strings.TrimPrefix(server.URL, "https://")
+
+	tests := []struct {
+		name       string
+		prober     *TLSProber
+		wantStatus bool
+		wantFailed string
+	}{
+		{
+			name:       "valid",
+			prober:     &TLSProber{ServerName: "example.com", RootCAs: roots},
+			wantStatus: true,
+		},
+		{
+			name:       "untrusted",
+			prober:     &TLSProber{ServerName: "example.com"},
+			wantFailed: "tls-verify",
+		},
+		{
+			name:       "other name",
+			prober:     &TLSProber{ServerName: "coinbase.com", RootCAs: roots},
+			wantFailed: "tls-verify",
+		},
+		{
+			name: "expiring",
+			prober: &TLSProber{
+				ServerName:  "example.com",
+				RootCAs:     roots,
+				MinValidity: time.Until(server.Certificate().NotAfter) + time.Hour,
+			},
+			wantFailed: "tls-min-validity",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := tt.prober.Probe(context.Background(), Request{URL: target})
+			if err != nil || result.Type != ServiceOnline {
+				t.Fatalf("Got %s and error [%v], want the handshake to succeed", result.Type, err)
+			}
+			if result.Status != tt.wantStatus {
+				t.Errorf("Got status %v, want %v", result.Status, tt.wantStatus)
+			}
+			if tt.wantFailed != "" &&
+				(len(result.FailedAssertions) != 1 || !strings.HasPrefix(result.FailedAssertions[0], tt.wantFailed)) {
+				t.Errorf("Got failed assertions %v, want %s", result.FailedAssertions, tt.wantFailed)
+			}
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package probe
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// WebhookSink posts Results as JSON to a URL.
+type WebhookSink struct {
+	URL string
+	// Client sends the requests, http.DefaultClient is used when it is nil.
+	Client *http.Client
+}
+
+func (s WebhookSink) Report(ctx context.Context, result Result) error {
+	body, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, s.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	response, err := client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return fmt.Errorf("webhook %s responded with %s", s.URL, response.Status)
+	}
+	return nil
+}
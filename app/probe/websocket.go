@@ -0,0 +1,311 @@
+package probe
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+// DefaultWebSocketWithin is how long WebSocketProber waits for messages
+// when neither it nor the request sets a deadline.
+const DefaultWebSocketWithin = 10 * time.Second
+
+// WebSocketProber subscribes to a WebSocket feed and checks that messages
+// keep coming and are fresh. Only messages carrying a server timestamp
+// count, so subscription confirmations and the like are skipped.
+type WebSocketProber struct {
+	// Subscribe is sent once connected, for example a ticker subscription.
+	Subscribe string
+	// Messages is how many messages must arrive, at least one.
+	Messages int
+	// Within is how long after subscribing the messages must arrive, until
+	// the request timeout when it is zero.
+	Within time.Duration
+	// MaxStaleness is the largest age of a message against its server
+	// timestamp, it is not checked when zero.
+	MaxStaleness time.Duration
+	// TimeField is the JSON path of the server timestamp, "time" when it
+	// is empty. Timestamps are RFC 3339 strings or Unix seconds.
+	TimeField string
+	// Proxy returns the proxy to CONNECT through for the http or https form
+	// of the target, the target is dialed directly when it is nil.
+	Proxy func(*http.Request) (*url.URL, error)
+	// Origin of the handshake, the http or https form of the target when
+	// it is empty.
+	Origin             string
+	InsecureSkipVerify bool
+	// Now returns the ping time, time.Now is used when it is nil.
+	Now func() time.Time
+}
+
+// Probe connects to the ws or wss target and waits for the messages. A
+// silent or lagging feed is online, with failed assertions. Assertions are
+// checked against the last message.
+func (p *WebSocketProber) Probe(ctx context.Context, request Request) (result Result, err error) {
+	result = newResult(pingTime(p.Now))
+	ctx, cancel := withTimeout(ctx, request.Timeout)
+	defer cancel()
+
+	var proxyURL *url.URL
+	if p.Proxy != nil {
+		lookup, err := http.NewRequest(http.MethodGet, httpURL(request.URL), nil)
+		if err != nil {
+			return result, err
+		}
+		if proxyURL, err = p.Proxy(lookup); err != nil {
+			return result, err
+		}
+	}
+	if proxyURL != nil {
+		result.Proxy = proxyURL.Redacted()
+	}
+	var dialErr error
+	defer func() {
+		if !result.Status {
+			var proxyErr *proxyError
+			result.markFailedHop(errors.As(dialErr, &proxyErr))
+		}
+	}()
+
+	start := time.Now()
+	ws, err := p.dial(ctx, request.URL, proxyURL)
+	dialErr = err
+	result.ConnectMs = time.Since(start).Milliseconds()
+	result.LatencyMs = result.ConnectMs
+	if err != nil {
+		return result, err
+	}
+	defer ws.Close()
+	if p.Subscribe != "" {
+		if err := websocket.Message.Send(ws, p.Subscribe); err != nil {
+			return result, err
+		}
+	}
+
+	subscribed := time.Now()
+	within := p.Within
+	if deadline, ok := ctx.Deadline(); ok && (within == 0 || deadline.Before(subscribed.Add(within))) {
+		within = deadline.Sub(subscribed)
+	}
+	if within <= 0 {
+		within = DefaultWebSocketWithin
+	}
+	if err := ws.SetDeadline(subscribed.Add(within)); err != nil {
+		return result, err
+	}
+	want := p.Messages
+	if want < 1 {
+		want = 1
+	}
+	received := 0
+	var staleness time.Duration
+	var last []byte
+	var receiveErr error
+	for received < want {
+		var message []byte
+		if receiveErr = websocket.Message.Receive(ws, &message); receiveErr != nil {
+			break
+		}
+		receivedAt := time.Now()
+		serverTime, ok := messageTime(message, p.TimeField)
+		if !ok {
+			continue
+		}
+		if received == 0 {
+			result.FirstMessageMs = receivedAt.Sub(subscribed).Milliseconds()
+		}
+		received++
+		if age := receivedAt.Sub(serverTime); age > staleness {
+			staleness = age
+		}
+		last = message
+	}
+	latency := time.Since(start)
+	result.LatencyMs = latency.Milliseconds()
+	result.StalenessMs = staleness.Milliseconds()
+
+	result.online(fmt.Sprintf(
+		"received %d of %d messages, connect %dms, first message %dms, staleness %dms",
+		received, want, result.ConnectMs, result.FirstMessageMs, result.StalenessMs,
+	))
+	if received < want {
+		result.fail("ws-messages=%d: got %d within %s: %v", want, received, within.Round(time.Millisecond), receiveErr)
+	}
+	if p.MaxStaleness > 0 && staleness > p.MaxStaleness {
+		result.fail("ws-max-staleness=%s: messages lag %s", p.MaxStaleness, staleness.Round(time.Millisecond))
+	}
+	result.assert(request.Assertions, Response{Body: last, Latency: latency})
+	return result, nil
+}
+
+// dial connects within ctx, which the websocket package does not support
+// itself, through proxyURL when it is set, and does the handshake on the
+// connection.
+func (p *WebSocketProber) dial(ctx context.Context, target string, proxyURL *url.URL) (*websocket.Conn, error) {
+	origin := p.Origin
+	if origin == "" {
+		origin = httpURL(target)
+	}
+	config, err := websocket.NewConfig(target, origin)
+	if err != nil {
+		return nil, err
+	}
+	defaultPort := "80"
+	switch config.Location.Scheme {
+	case "ws":
+	case "wss":
+		defaultPort = "443"
+	default:
+		return nil, fmt.Errorf("target %q must be a ws or wss URL", target)
+	}
+	addr, err := address(target, defaultPort)
+	if err != nil {
+		return nil, err
+	}
+
+	var conn net.Conn
+	if proxyURL != nil {
+		conn, err = connectThrough(ctx, proxyURL, addr)
+	} else {
+		conn, err = (&net.Dialer{}).DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	if config.Location.Scheme == "wss" {
+		tlsConn := tls.Client(conn, &tls.Config{
+			ServerName:         config.Location.Hostname(),
+			InsecureSkipVerify: p.InsecureSkipVerify,
+		})
+		if err := tlsConn.Handshake(); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		conn = tlsConn
+	}
+	ws, err := websocket.NewClient(config, conn)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return ws, nil
+}
+
+// httpURL returns the http or https form of a ws or wss URL, which proxy
+// settings and origins are given for.
+func httpURL(target string) string {
+	return strings.Replace(strings.Replace(target, "wss://", "https://", 1), "ws://", "http://", 1)
+}
+
+// proxyError marks failures of the proxy, as opposed to the target.
+type proxyError struct {
+	err error
+}
+
+func (e *proxyError) Error() string {
+	return "proxy: " + e.err.Error()
+}
+
+func (e *proxyError) Unwrap() error {
+	return e.err
+}
+
+// connectThrough opens a tunnel to addr with a CONNECT request to an http
+// or https proxy, authenticated with the credentials of proxyURL.
+func connectThrough(ctx context.Context, proxyURL *url.URL, addr string) (net.Conn, error) {
+	var conn net.Conn
+	var err error
+	switch proxyURL.Scheme {
+	case "http":
+		proxyAddr, addrErr := address(proxyURL.String(), "80")
+		if addrErr != nil {
+			return nil, &proxyError{err: addrErr}
+		}
+		conn, err = (&net.Dialer{}).DialContext(ctx, "tcp", proxyAddr)
+	case "https":
+		proxyAddr, addrErr := address(proxyURL.String(), "443")
+		if addrErr != nil {
+			return nil, &proxyError{err: addrErr}
+		}
+		dialer := &tls.Dialer{Config: &tls.Config{ServerName: proxyURL.Hostname()}}
+		conn, err = dialer.DialContext(ctx, "tcp", proxyAddr)
+	default:
+		return nil, &proxyError{err: fmt.Errorf("unsupported proxy scheme %q", proxyURL.Scheme)}
+	}
+	if err != nil {
+		return nil, &proxyError{err: err}
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	connect := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: addr},
+		Host:   addr,
+		Header: http.Header{},
+	}
+	if user := proxyURL.User; user != nil {
+		password, _ := user.Password()
+		credentials := base64.StdEncoding.EncodeToString([]byte(user.Username() + ":" + password))
+		connect.Header.Set("Proxy-Authorization", "Basic "+credentials)
+	}
+	if err := connect.Write(conn); err != nil {
+		conn.Close()
+		return nil, &proxyError{err: err}
+	}
+	// the target speaks only after the client, so nothing is buffered past
+	// the response
+	response, err := http.ReadResponse(bufio.NewReader(conn), connect)
+	if err != nil {
+		conn.Close()
+		return nil, &proxyError{err: err}
+	}
+	if response.StatusCode != http.StatusOK {
+		response.Body.Close()
+		conn.Close()
+		return nil, &proxyError{err: fmt.Errorf("CONNECT %s: %s", addr, response.Status)}
+	}
+	return conn, nil
+}
+
+// messageTime returns the server timestamp at the path of a JSON message.
+func messageTime(message []byte, path string) (time.Time, bool) {
+	if path == "" {
+		path = "time"
+	}
+	var node interface{}
+	if err := json.Unmarshal(message, &node); err != nil {
+		return time.Time{}, false
+	}
+	for _, key := range strings.Split(path, ".") {
+		object, ok := node.(map[string]interface{})
+		if !ok {
+			return time.Time{}, false
+		}
+		node = object[key]
+	}
+	switch value := node.(type) {
+	case string:
+		t, err := time.Parse(time.RFC3339Nano, value)
+		return t, err == nil
+	case float64:
+		seconds := int64(value)
+		return time.Unix(seconds, int64((value-float64(seconds))*1e9)), true
+	}
+	return time.Time{}, false
+}
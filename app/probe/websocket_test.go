@@ -0,0 +1,173 @@
+package probe
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+// tickerStub serves a feed which confirms the subscription and then sends
+// count ticker messages, lag old.
+func tickerStub(count int, lag time.Duration) *httptest.Server {
+	return httptest.NewServer(websocket.Handler(func(ws *websocket.Conn) {
+		var subscribe string
+		if err := websocket.Message.Receive(ws, &subscribe); err != nil {
+			return
+		}
+		websocket.Message.Send(ws, `{"type":"subscriptions","channels":[{"name":"ticker"}]}`)
+		for i := 0; i < count; i++ {
+			serverTime := time.Now().Add(-lag).UTC().Format(time.RFC3339Nano)
+			websocket.Message.Send(ws, fmt.Sprintf(
+				`{"type":"ticker","sequence":%d,"product_id":"BTC-USD","price":"42000.01","time":%q}`,
+				i, serverTime,
+			))
+		}
+		// stay connected and silent like a stalled feed
+		var ignored string
+		websocket.Message.Receive(ws, &ignored)
+	}))
+}
+
+func TestWebSocketProber_Probe(t *testing.T) {
+	subscribe := `{"type":"subscribe","product_ids":["BTC-USD"],"channels":["ticker"]}`
+	tests := []struct {
+		name       string
+		count      int
+		lag        time.Duration
+		assertions []Assertion
+		wantStatus bool
+		wantFailed string
+	}{
+		{
+			name:       "fresh",
+			count:      3,
+			assertions: []Assertion{{Kind: JSONAssertion, Argument: "product_id:BTC-USD"}},
+			wantStatus: true,
+		},
+		{name: "silent", count: 1, wantFailed: "ws-messages=3: got 1"},
+		{name: "lagging", count: 3, lag: time.Minute, wantFailed: "ws-max-staleness=5s"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := tickerStub(tt.count, tt.lag)
+			defer server.Close()
+			prober := &WebSocketProber{
+				Subscribe:    subscribe,
+				Messages:     3,
+				Within:       300 * time.Millisecond,
+				MaxStaleness: 5 * time.Second,
+			}
+			request := Request{
+				URL:        strings.Replace(server.URL, "http://", "ws://", 1),
+				Timeout:    5 * time.Second,
+				Assertions: tt.assertions,
+			}
+			result, err := prober.Probe(context.Background(), request)
+			if err != nil || result.Type != ServiceOnline || result.Status != tt.wantStatus {
+				t.Fatalf("Got (%s, %v) and error [%v], want online with status %v",
+					result.Type, result.Status, err, tt.wantStatus)
+			}
+			if tt.wantFailed != "" &&
+				(len(result.FailedAssertions) != 1 || !strings.HasPrefix(result.FailedAssertions[0], tt.wantFailed)) {
+				t.Errorf("Got failed assertions %v, want %s", result.FailedAssertions, tt.wantFailed)
+			}
+			if tt.lag > 0 && result.StalenessMs < tt.lag.Milliseconds() {
+				t.Errorf("Got staleness %dms, want at least %s", result.StalenessMs, tt.lag)
+			}
+		})
+	}
+}
+
+// connectProxy tunnels CONNECT requests which carry credentials and refuses
+// the others.
+func connectProxy(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodConnect || r.Header.Get("Proxy-Authorization") == "" {
+			w.WriteHeader(http.StatusProxyAuthRequired)
+			return
+		}
+		target, err := net.Dial("tcp", r.Host)
+		if err != nil {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		client, buffered, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		go func() {
+			io.Copy(target, buffered)
+			target.Close()
+		}()
+		io.Copy(client, target)
+		client.Close()
+	}))
+}
+
+func TestWebSocketProber_Probe_proxy(t *testing.T) {
+	server := tickerStub(1, 0)
+	defer server.Close()
+	proxy := connectProxy(t)
+	defer proxy.Close()
+	proxyURL, _ := url.Parse(proxy.URL)
+	request := Request{URL: strings.Replace(server.URL, "http://", "ws://", 1), Timeout: 5 * time.Second}
+
+	prober := &WebSocketProber{
+		Subscribe: "{}",
+		Within:    time.Second,
+		Proxy:     ProxyWithCredentials(http.ProxyURL(proxyURL), "user", "secret"),
+	}
+	result, err := prober.Probe(context.Background(), request)
+	if err != nil || !result.Status || result.Proxy == "" || strings.Contains(result.Proxy, "secret") {
+		t.Errorf("Got (%v, proxy [%s]) and error [%v], want the feed through the proxy", result.Status, result.Proxy, err)
+	}
+
+	prober.Proxy = http.ProxyURL(proxyURL)
+	result, err = prober.Probe(context.Background(), request)
+	if err == nil || result.Type != ServiceUnknown || result.Reason != ProxyFailed || result.FailedHop != ProxyHop {
+		t.Errorf("Got (%s, %s, %q) and error [%v], want the proxy failed",
+			result.Type, result.Reason, result.FailedHop, err)
+	}
+}
+
+func TestWebSocketProber_Probe_offline(t *testing.T) {
+	server := tickerStub(0, 0)
+	target := strings.Replace(server.URL, "http://", "ws://", 1)
+	server.Close()
+
+	result, err := (&WebSocketProber{}).Probe(context.Background(), Request{URL: target, Timeout: time.Second})
+	if err == nil || result.Type != ServiceOffline {
+		t.Errorf("Got %s and error [%v], want the closed feed offline", result.Type, err)
+	}
+}
+
+func Test_messageTime(t *testing.T) {
+	expected := time.Date(2021, 9, 1, 12, 0, 0, 500000000, time.UTC)
+	tests := map[string]bool{
+		`{"time":"2021-09-01T12:00:00.5Z"}`: true,
+		`{"data":{"ts":1630497600.5}}`:      true,
+		`{"type":"subscriptions"}`:          false,
+		`not json`:                          false,
+	}
+	for message, wantFound := range tests {
+		path := "time"
+		if strings.Contains(message, "ts") {
+			path = "data.ts"
+		}
+		got, found := messageTime([]byte(message), path)
+		if found != wantFound || (found && !got.Equal(expected)) {
+			t.Errorf("Got (%s, %v) for %s, want found %v", got, found, message, wantFound)
+		}
+	}
+}
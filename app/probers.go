@@ -0,0 +1,36 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/kalynv/coinbase-pinger/app/probe"
+)
+
+// newProber builds the prober of the configured probe type. Only HTTP and
+// WebSocket probes go through a proxy.
+func newProber(c config) (probe.Prober, error) {
+	proxy := probe.ProxyWithCredentials(http.ProxyFromEnvironment, c.ProxyUsername, c.ProxyPassword)
+	switch c.Probe {
+	case probe.HTTPProbe:
+		return probe.NewHTTPProber(c.InsecureSkipVerify, proxy), nil
+	case probe.TCPProbe:
+		return &probe.TCPProber{}, nil
+	case probe.DNSProbe:
+		return probe.NewDNSProber(c.DNSRecordType, c.DNSServer)
+	case probe.TLSProbe:
+		return &probe.TLSProber{ServerName: c.TLSServerName, MinValidity: c.TLSMinValidity}, nil
+	case probe.WebSocketProbe:
+		prober := c.WebSocket
+		prober.InsecureSkipVerify = c.InsecureSkipVerify
+		prober.Proxy = proxy
+		return &prober, nil
+	case probe.GRPCProbe:
+		return &probe.GRPCHealthProber{
+			Service:            c.GRPCService,
+			TLS:                c.GRPCTLS,
+			InsecureSkipVerify: c.InsecureSkipVerify,
+		}, nil
+	}
+	return nil, fmt.Errorf("unknown probe %q", c.Probe)
+}
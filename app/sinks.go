@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/kalynv/coinbase-pinger/app/probe"
+)
+
+// newSink builds the sink for the configured output, plus the termination
+// log and webhook sinks when they are set. The termination log goes first, so
+// it holds the result even when the output fails.
+func newSink(c config, output io.Writer) (probe.ResultSink, error) {
+	sink, err := newOutputSink(c, output)
+	if err != nil {
+		return nil, err
+	}
+	sinks := probe.MultiSink{}
+	if c.TerminationLog != "" {
+		sinks = append(sinks, probe.TerminationLogSink{Path: c.TerminationLog})
+	}
+	sinks = append(sinks, sink)
+	if c.WebhookURL != "" {
+		sinks = append(sinks, probe.WebhookSink{URL: c.WebhookURL})
+	}
+	return sinks, nil
+}
+
+func newOutputSink(c config, output io.Writer) (probe.ResultSink, error) {
+	switch c.Output {
+	case StdoutOutput:
+		return probe.WriterSink{Writer: output}, nil
+	case JSONOutput:
+		return probe.WriterSink{Writer: output, JSON: true}, nil
+	case K8sPodOutput:
+		return newPodSink(c)
+	case K8sStatusOutput:
+		return newStatusSink(c)
+	}
+	return nil, fmt.Errorf("unknown output %q", c.Output)
+}
+
+func restConfig(c config) (*rest.Config, error) {
+	if c.Kubeconfig == "" {
+		return rest.InClusterConfig()
+	}
+	return clientcmd.BuildConfigFromFlags("", c.Kubeconfig)
+}
+
+func newPodSink(c config) (probe.ResultSink, error) {
+	namespace, err := getNamespace()
+	if err != nil {
+		return nil, err
+	}
+	podName, err := getPodName()
+	if err != nil {
+		return nil, err
+	}
+	config, err := restConfig(c)
+	if err != nil {
+		return nil, err
+	}
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	return probe.PodSink{Clientset: clientset, Namespace: namespace, Name: podName}, nil
+}
+
+func newStatusSink(c config) (probe.ResultSink, error) {
+	namespace := c.PingerNamespace
+	if namespace == "" {
+		podNamespace, err := getNamespace()
+		if err != nil {
+			return nil, fmt.Errorf("--pinger-namespace is not set: %w", err)
+		}
+		namespace = podNamespace
+	}
+	config, err := restConfig(c)
+	if err != nil {
+		return nil, err
+	}
+	client, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	return probe.StatusSink{Client: client, Namespace: namespace, Name: c.PingerName}, nil
+}
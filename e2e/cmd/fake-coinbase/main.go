@@ -0,0 +1,113 @@
+// Command fake-coinbase serves the Coinbase v2 public price API for
+// clusters without egress, demos and chaos testing. Point a CoinbasePinger
+// at it and inject faults through the admin API, see
+// fakecoinbase.Server.AdminHandler.
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"flag"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/kalynv/coinbase-pinger/e2e/fakecoinbase"
+)
+
+type config struct {
+	Addr         string
+	TLSAddr      string
+	TLSHosts     string
+	AdminAddr    string
+	Seed         int64
+	Volatility   float64
+	WalkInterval time.Duration
+}
+
+func parseConfig(args []string) (config, error) {
+	c := config{}
+	flags := flag.NewFlagSet("fake-coinbase", flag.ContinueOnError)
+	flags.StringVar(&c.Addr, "addr", ":8080", "Address the price API listens on.")
+	flags.StringVar(&c.TLSAddr, "tls-addr", ":8443", "Address the price API listens on with TLS, empty disables it.")
+	flags.StringVar(&c.TLSHosts, "tls-hosts", "localhost,127.0.0.1",
+		"Comma separated host names and IPs of the self-signed certificate.")
+	flags.StringVar(&c.AdminAddr, "admin-addr", ":8081", "Address the fault injection admin API listens on.")
+	flags.Int64Var(&c.Seed, "seed", 1, "Seed of the random walk and error rate, for repeatable runs.")
+	flags.Float64Var(&c.Volatility, "volatility", 0,
+		"Largest relative price step of the random walk, e.g. 0.01. Zero keeps prices fixed.")
+	flags.DurationVar(&c.WalkInterval, "walk-interval", time.Second, "Interval between random walk steps.")
+	err := flags.Parse(args)
+	return c, err
+}
+
+func main() {
+	c, err := parseConfig(os.Args[1:])
+	if err == flag.ErrHelp {
+		os.Exit(0)
+	}
+	if err != nil {
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	fake := fakecoinbase.NewWithSeed(c.Seed)
+	servers := []*http.Server{
+		{Addr: c.Addr, Handler: fake},
+		{Addr: c.AdminAddr, Handler: fake.AdminHandler()},
+	}
+	if c.TLSAddr != "" {
+		tlsConfig, err := fake.TLSConfig(strings.Split(c.TLSHosts, ",")...)
+		if err != nil {
+			log.Fatalf("unable to generate certificates: %v", err)
+		}
+		servers = append(servers, &http.Server{Addr: c.TLSAddr, Handler: fake, TLSConfig: tlsConfig})
+	}
+
+	for _, server := range servers {
+		go serve(server)
+	}
+	if c.Volatility > 0 {
+		go walk(ctx, fake, c.Volatility, c.WalkInterval)
+	}
+
+	<-ctx.Done()
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	for _, server := range servers {
+		_ = server.Shutdown(shutdownCtx)
+	}
+}
+
+func serve(server *http.Server) {
+	log.Printf("listening on %s", server.Addr)
+	var err error
+	if server.TLSConfig != nil {
+		server.TLSConfig.MinVersion = tls.VersionTLS12
+		err = server.ListenAndServeTLS("", "")
+	} else {
+		err = server.ListenAndServe()
+	}
+	if err != nil && err != http.ErrServerClosed {
+		log.Fatalf("server on %s failed: %v", server.Addr, err)
+	}
+}
+
+func walk(ctx context.Context, fake *fakecoinbase.Server, volatility float64, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			fake.Walk(volatility)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package fakecoinbase
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// behaviorJSON is the admin API form of Behavior, with the latency written
+// as a duration, e.g. "250ms".
+type behaviorJSON struct {
+	Latency            string  `json:"latency,omitempty"`
+	StatusCode         int     `json:"statusCode,omitempty"`
+	ErrorRate          float64 `json:"errorRate,omitempty"`
+	RateLimit          int     `json:"rateLimit,omitempty"`
+	Malformed          bool    `json:"malformed,omitempty"`
+	ExpiredCertificate bool    `json:"expiredCertificate,omitempty"`
+}
+
+// AdminHandler serves the fault injection API:
+//
+//	GET, PUT /admin/behavior   current Behavior, PUT replaces it
+//	DELETE   /admin/behavior   resets to serving normally
+//	GET, PUT /admin/prices     spot prices by pair, PUT merges them
+//
+// It should not be exposed next to the price endpoints.
+func (s *Server) AdminHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/admin/behavior", s.serveBehavior)
+	mux.HandleFunc("/admin/prices", s.servePrices)
+	return mux
+}
+
+func (s *Server) serveBehavior(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		var body behaviorJSON
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		behavior, err := body.behavior()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		s.SetBehavior(behavior)
+	case http.MethodDelete:
+		s.SetBehavior(Behavior{})
+	default:
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	writeJSON(w, newBehaviorJSON(s.Behavior()))
+}
+
+func (s *Server) servePrices(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		var prices map[string]float64
+		if err := json.NewDecoder(r.Body).Decode(&prices); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		for pair, spot := range prices {
+			s.SetPrice(pair, spot)
+		}
+	default:
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	writeJSON(w, s.Prices())
+}
+
+func newBehaviorJSON(b Behavior) behaviorJSON {
+	body := behaviorJSON{
+		StatusCode:         b.StatusCode,
+		ErrorRate:          b.ErrorRate,
+		RateLimit:          b.RateLimit,
+		Malformed:          b.Malformed,
+		ExpiredCertificate: b.ExpiredCertificate,
+	}
+	if b.Latency > 0 {
+		body.Latency = b.Latency.String()
+	}
+	return body
+}
+
+func (body behaviorJSON) behavior() (Behavior, error) {
+	b := Behavior{
+		StatusCode:         body.StatusCode,
+		ErrorRate:          body.ErrorRate,
+		RateLimit:          body.RateLimit,
+		Malformed:          body.Malformed,
+		ExpiredCertificate: body.ExpiredCertificate,
+	}
+	if body.Latency != "" {
+		latency, err := time.ParseDuration(body.Latency)
+		if err != nil {
+			return b, err
+		}
+		b.Latency = latency
+	}
+	return b, nil
+}
+
+func writeJSON(w http.ResponseWriter, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(value)
+}
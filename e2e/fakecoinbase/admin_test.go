@@ -0,0 +1,86 @@
+package fakecoinbase
+
+import (
+	"crypto/tls"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAdminHandler(t *testing.T) {
+	fake := New()
+	admin := httptest.NewServer(fake.AdminHandler())
+	defer admin.Close()
+
+	put := func(path, body string) (int, string) {
+		request, err := http.NewRequest(http.MethodPut, admin.URL+path, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		response, err := http.DefaultClient.Do(request)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer response.Body.Close()
+		data, _ := io.ReadAll(response.Body)
+		return response.StatusCode, strings.TrimSpace(string(data))
+	}
+
+	code, body := put("/admin/behavior", `{"latency":"250ms","errorRate":0.1,"rateLimit":5}`)
+	if code != http.StatusOK || body != `{"latency":"250ms","errorRate":0.1,"rateLimit":5}` {
+		t.Errorf("Got %d [%s]", code, body)
+	}
+	want := Behavior{Latency: 250 * time.Millisecond, ErrorRate: 0.1, RateLimit: 5}
+	if fake.Behavior() != want {
+		t.Errorf("Got behavior %+v, want %+v", fake.Behavior(), want)
+	}
+
+	if code, _ := put("/admin/behavior", `{"latency":"soon"}`); code != http.StatusBadRequest {
+		t.Errorf("Got %d for an invalid latency, want %d", code, http.StatusBadRequest)
+	}
+
+	if code, _ := put("/admin/prices", `{"BTC-USD":42}`); code != http.StatusOK {
+		t.Errorf("Got %d setting prices", code)
+	}
+	if spot := fake.Prices()["BTC-USD"]; spot != 42 {
+		t.Errorf("Got BTC-USD %f, want 42", spot)
+	}
+}
+
+func TestTLSConfig(t *testing.T) {
+	fake := New()
+	tlsConfig, err := fake.TLSConfig("127.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// httptest.Server.StartTLS would replace the certificate
+	server := httptest.NewUnstartedServer(fake)
+	server.Listener = tls.NewListener(server.Listener, tlsConfig)
+	server.Start()
+	defer server.Close()
+	url := strings.Replace(server.URL, "http://", "https://", 1)
+
+	var seen []time.Time
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{
+		InsecureSkipVerify: true,
+		VerifyConnection: func(state tls.ConnectionState) error {
+			seen = append(seen, state.PeerCertificates[0].NotAfter)
+			return nil
+		},
+	}}}
+	for _, expired := range []bool{false, true} {
+		fake.SetBehavior(Behavior{ExpiredCertificate: expired})
+		client.CloseIdleConnections()
+		response, err := client.Get(url + "/v2/time")
+		if err != nil {
+			t.Fatal(err)
+		}
+		response.Body.Close()
+	}
+	if len(seen) != 2 || !seen[0].After(time.Now()) || !seen[1].Before(time.Now()) {
+		t.Errorf("Got certificates expiring at %v, want a valid then an expired one", seen)
+	}
+}
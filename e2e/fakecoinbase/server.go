@@ -0,0 +1,341 @@
+// Package fakecoinbase serves the Coinbase v2 public price, currencies,
+// exchange-rates and time endpoints in process, with scriptable latency,
+// error codes, rate limiting and malformed bodies.
+package fakecoinbase
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// spread is applied to the spot price for buy and sell prices.
+const spread = 0.005
+
+// Behavior scripts how price requests are answered.
+type Behavior struct {
+	// Latency delays every answer.
+	Latency time.Duration
+	// StatusCode answers with this code and a Coinbase style error body,
+	// when it is not zero.
+	StatusCode int
+	// ErrorRate is the fraction of requests answered with 503, from 0 to 1.
+	ErrorRate float64
+	// RateLimit answers requests over this many per second with 429.
+	RateLimit int
+	// Malformed answers with a truncated JSON body.
+	Malformed bool
+	// ExpiredCertificate serves an expired certificate over TLS, see
+	// Server.TLSConfig.
+	ExpiredCertificate bool
+}
+
+// Server is an http.Handler mimicking the Coinbase v2 public API:
+//
+//	/v2/prices/{pair}/{buy|sell|spot}, /v2/currencies,
+//	/v2/exchange-rates?currency={code}, /v2/time
+//
+// Serve it with httptest.NewServer or httptest.NewTLSServer.
+type Server struct {
+	mu       sync.Mutex
+	prices   map[string]float64
+	behavior Behavior
+	requests int
+	rand     *rand.Rand
+	// requests in the current rate limit window
+	window      time.Time
+	windowCount int
+	// now returns the server time, time.Now is used when it is nil
+	now func() time.Time
+}
+
+// New returns a Server with BTC-USD and ETH-USD spot prices.
+func New() *Server {
+	return NewWithSeed(1)
+}
+
+// NewWithSeed returns a Server as New does. Random walks and error rates
+// are driven by a generator seeded with seed, so runs are repeatable.
+func NewWithSeed(seed int64) *Server {
+	return &Server{
+		prices: map[string]float64{
+			"BTC-USD": 50000,
+			"ETH-USD": 3500,
+		},
+		rand: rand.New(rand.NewSource(seed)),
+	}
+}
+
+// SetPrice sets the spot price of the pair, e.g. BTC-USD.
+func (s *Server) SetPrice(pair string, spot float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.prices[pair] = spot
+}
+
+// SetBehavior changes how following requests are answered.
+func (s *Server) SetBehavior(behavior Behavior) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.behavior = behavior
+}
+
+// Behavior returns how requests are answered.
+func (s *Server) Behavior() Behavior {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.behavior
+}
+
+// Prices returns a copy of the spot prices.
+func (s *Server) Prices() map[string]float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	prices := make(map[string]float64, len(s.prices))
+	for pair, spot := range s.prices {
+		prices[pair] = spot
+	}
+	return prices
+}
+
+// Walk moves every spot price by a random step of at most volatility,
+// relative to the price, e.g. 0.01 for 1%.
+func (s *Server) Walk(volatility float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// stepped in order, so the same seed walks the same way
+	pairs := make([]string, 0, len(s.prices))
+	for pair := range s.prices {
+		pairs = append(pairs, pair)
+	}
+	sort.Strings(pairs)
+	for _, pair := range pairs {
+		s.prices[pair] *= 1 + volatility*(2*s.rand.Float64()-1)
+	}
+}
+
+// Requests returns the number of requests served so far.
+func (s *Server) Requests() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests
+}
+
+type price struct {
+	Base     string `json:"base"`
+	Currency string `json:"currency"`
+	Amount   string `json:"amount"`
+}
+
+type apiError struct {
+	ID      string `json:"id"`
+	Message string `json:"message"`
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	behavior, limited, failed := s.admit()
+
+	if behavior.Latency > 0 {
+		select {
+		case <-time.After(behavior.Latency):
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	switch {
+	case limited:
+		w.Header().Set("Retry-After", "1")
+		writeError(w, http.StatusTooManyRequests, "rate_limit_exceeded", "Too many requests")
+		return
+	case failed:
+		writeError(w, http.StatusServiceUnavailable, "internal_server_error", http.StatusText(http.StatusServiceUnavailable))
+		return
+	case behavior.StatusCode != 0:
+		writeError(w, behavior.StatusCode, "internal_server_error", http.StatusText(behavior.StatusCode))
+		return
+	case behavior.Malformed:
+		fmt.Fprint(w, `{"data":{"base":"BTC","curr`)
+		return
+	}
+
+	switch path := strings.TrimRight(r.URL.Path, "/"); {
+	case strings.HasPrefix(path, "/v2/prices/"):
+		s.servePrice(w, path)
+	case path == "/v2/currencies":
+		s.serveCurrencies(w)
+	case path == "/v2/exchange-rates":
+		s.serveExchangeRates(w, r.URL.Query().Get("currency"))
+	case path == "/v2/time":
+		s.serveTime(w)
+	default:
+		writeError(w, http.StatusNotFound, "not_found", "Not found")
+	}
+}
+
+// admit counts the request and decides on injected faults.
+func (s *Server) admit() (behavior Behavior, limited, failed bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests++
+	behavior = s.behavior
+
+	if behavior.RateLimit > 0 {
+		now := s.timeNow().Truncate(time.Second)
+		if !now.Equal(s.window) {
+			s.window, s.windowCount = now, 0
+		}
+		s.windowCount++
+		limited = s.windowCount > behavior.RateLimit
+	}
+	failed = behavior.ErrorRate > 0 && s.rand.Float64() < behavior.ErrorRate
+	return behavior, limited, failed
+}
+
+func (s *Server) timeNow() time.Time {
+	if s.now == nil {
+		return time.Now()
+	}
+	return s.now()
+}
+
+func (s *Server) servePrice(w http.ResponseWriter, path string) {
+	pair, kind, ok := parsePricePath(path)
+	if !ok {
+		writeError(w, http.StatusNotFound, "not_found", "Not found")
+		return
+	}
+	amount, err := s.price(pair, kind)
+	if err != nil {
+		writeError(w, http.StatusNotFound, "not_found", err.Error())
+		return
+	}
+	base, currency := splitPair(pair)
+	writeData(w, price{Base: base, Currency: currency, Amount: amount})
+}
+
+type currency struct {
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	MinSize string `json:"min_size"`
+}
+
+var currencyNames = map[string]string{
+	"BTC": "Bitcoin",
+	"ETH": "Ethereum",
+	"EUR": "Euro",
+	"USD": "United States Dollar",
+}
+
+// serveCurrencies lists the currencies of all known pairs.
+func (s *Server) serveCurrencies(w http.ResponseWriter) {
+	ids := map[string]bool{}
+	for pair := range s.Prices() {
+		base, quote := splitPair(pair)
+		ids[base], ids[quote] = true, true
+	}
+	currencies := []currency{}
+	for id := range ids {
+		name := currencyNames[id]
+		if name == "" {
+			name = id
+		}
+		currencies = append(currencies, currency{ID: id, Name: name, MinSize: "0.01"})
+	}
+	sort.Slice(currencies, func(i, j int) bool { return currencies[i].ID < currencies[j].ID })
+	writeData(w, currencies)
+}
+
+type exchangeRates struct {
+	Currency string            `json:"currency"`
+	Rates    map[string]string `json:"rates"`
+}
+
+// serveExchangeRates answers the rates of the currency against every
+// currency it is paired with, in either direction.
+func (s *Server) serveExchangeRates(w http.ResponseWriter, code string) {
+	code = strings.ToUpper(code)
+	if code == "" {
+		code = "USD"
+	}
+	rates := map[string]string{code: "1"}
+	for pair, spot := range s.Prices() {
+		base, quote := splitPair(pair)
+		switch code {
+		case base:
+			rates[quote] = fmt.Sprintf("%.2f", spot)
+		case quote:
+			rates[base] = fmt.Sprintf("%.8f", 1/spot)
+		}
+	}
+	if len(rates) == 1 {
+		writeError(w, http.StatusBadRequest, "invalid_request", "Invalid currency")
+		return
+	}
+	writeData(w, exchangeRates{Currency: code, Rates: rates})
+}
+
+type serverTime struct {
+	ISO   string `json:"iso"`
+	Epoch int64  `json:"epoch"`
+}
+
+func (s *Server) serveTime(w http.ResponseWriter) {
+	now := s.timeNow().UTC()
+	writeData(w, serverTime{ISO: now.Format(time.RFC3339), Epoch: now.Unix()})
+}
+
+// price formats the buy, sell or spot price of the pair.
+func (s *Server) price(pair, kind string) (string, error) {
+	s.mu.Lock()
+	spot, known := s.prices[pair]
+	s.mu.Unlock()
+	if !known {
+		return "", fmt.Errorf("Invalid currency pair %s", pair)
+	}
+	switch kind {
+	case "buy":
+		spot *= 1 + spread
+	case "sell":
+		spot *= 1 - spread
+	}
+	return fmt.Sprintf("%.2f", spot), nil
+}
+
+// parsePricePath splits /v2/prices/{pair}/{buy|sell|spot}.
+func parsePricePath(path string) (pair, kind string, ok bool) {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	if len(parts) != 4 || parts[0] != "v2" || parts[1] != "prices" {
+		return "", "", false
+	}
+	switch parts[3] {
+	case "buy", "sell", "spot":
+		return strings.ToUpper(parts[2]), parts[3], true
+	}
+	return "", "", false
+}
+
+func splitPair(pair string) (base, currency string) {
+	i := strings.Index(pair, "-")
+	if i < 0 {
+		return pair, ""
+	}
+	return pair[:i], pair[i+1:]
+}
+
+func writeData(w http.ResponseWriter, data interface{}) {
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": data})
+}
+
+func writeError(w http.ResponseWriter, code int, id, message string) {
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"errors": []apiError{{ID: id, Message: message}},
+	})
+}
@@ -0,0 +1,196 @@
+package fakecoinbase
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func get(t *testing.T, ctx context.Context, url string) (int, string) {
+	t.Helper()
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return 0, err.Error()
+	}
+	defer response.Body.Close()
+	body, _ := io.ReadAll(response.Body)
+	return response.StatusCode, strings.TrimSpace(string(body))
+}
+
+func TestServer(t *testing.T) {
+	fake := New()
+	fake.SetPrice("BTC-USD", 100)
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	tests := []struct {
+		name     string
+		behavior Behavior
+		path     string
+		timeout  time.Duration
+		wantCode int
+		wantBody string
+	}{
+		{
+			name:     "spot price",
+			path:     "/v2/prices/BTC-USD/spot",
+			wantCode: http.StatusOK,
+			wantBody: `{"data":{"base":"BTC","currency":"USD","amount":"100.00"}}`,
+		},
+		{
+			name:     "buy price",
+			path:     "/v2/prices/btc-usd/buy",
+			wantCode: http.StatusOK,
+			wantBody: `{"data":{"base":"BTC","currency":"USD","amount":"100.50"}}`,
+		},
+		{
+			name:     "unknown pair",
+			path:     "/v2/prices/XXX-USD/sell",
+			wantCode: http.StatusNotFound,
+			wantBody: `{"errors":[{"id":"not_found","message":"Invalid currency pair XXX-USD"}]}`,
+		},
+		{
+			name:     "scripted error",
+			behavior: Behavior{StatusCode: http.StatusServiceUnavailable},
+			path:     "/v2/prices/BTC-USD/spot",
+			wantCode: http.StatusServiceUnavailable,
+			wantBody: `{"errors":[{"id":"internal_server_error","message":"Service Unavailable"}]}`,
+		},
+		{
+			name:     "malformed JSON",
+			behavior: Behavior{Malformed: true},
+			path:     "/v2/prices/BTC-USD/spot",
+			wantCode: http.StatusOK,
+			wantBody: `{"data":{"base":"BTC","curr`,
+		},
+		{
+			name:     "latency",
+			behavior: Behavior{Latency: time.Second},
+			path:     "/v2/prices/BTC-USD/spot",
+			timeout:  50 * time.Millisecond,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake.SetBehavior(tt.behavior)
+			ctx := context.Background()
+			if tt.timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tt.timeout)
+				defer cancel()
+			}
+			code, body := get(t, ctx, server.URL+tt.path)
+			if code != tt.wantCode {
+				t.Errorf("Got status %d, want %d", code, tt.wantCode)
+			}
+			if tt.wantCode != 0 && body != tt.wantBody {
+				t.Errorf("Got body [%s], want [%s]", body, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestServerEndpoints(t *testing.T) {
+	fake := New()
+	fake.SetPrice("BTC-USD", 100)
+	fake.SetPrice("ETH-USD", 4)
+	fake.now = func() time.Time { return time.Date(2021, 9, 1, 12, 0, 0, 0, time.UTC) }
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	tests := []struct {
+		path     string
+		wantCode int
+		wantBody string
+	}{
+		{
+			path:     "/v2/currencies",
+			wantCode: http.StatusOK,
+			wantBody: `{"data":[{"id":"BTC","name":"Bitcoin","min_size":"0.01"},` +
+				`{"id":"ETH","name":"Ethereum","min_size":"0.01"},` +
+				`{"id":"USD","name":"United States Dollar","min_size":"0.01"}]}`,
+		},
+		{
+			path:     "/v2/exchange-rates?currency=btc",
+			wantCode: http.StatusOK,
+			wantBody: `{"data":{"currency":"BTC","rates":{"BTC":"1","USD":"100.00"}}}`,
+		},
+		{
+			path:     "/v2/exchange-rates",
+			wantCode: http.StatusOK,
+			wantBody: `{"data":{"currency":"USD","rates":{"BTC":"0.01000000","ETH":"0.25000000","USD":"1"}}}`,
+		},
+		{
+			path:     "/v2/time",
+			wantCode: http.StatusOK,
+			wantBody: `{"data":{"iso":"2021-09-01T12:00:00Z","epoch":1630497600}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			code, body := get(t, context.Background(), server.URL+tt.path)
+			if code != tt.wantCode || body != tt.wantBody {
+				t.Errorf("Got %d [%s], want %d [%s]", code, body, tt.wantCode, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestServerFaults(t *testing.T) {
+	fake := New()
+	server := httptest.NewServer(fake)
+	defer server.Close()
+	url := server.URL + "/v2/prices/BTC-USD/spot"
+
+	t.Run("rate limit", func(t *testing.T) {
+		fake.now = func() time.Time { return time.Date(2021, 9, 1, 12, 0, 0, 0, time.UTC) }
+		defer func() { fake.now = nil }()
+		fake.SetBehavior(Behavior{RateLimit: 2})
+		codes := []int{}
+		for i := 0; i < 3; i++ {
+			code, _ := get(t, context.Background(), url)
+			codes = append(codes, code)
+		}
+		if codes[1] != http.StatusOK || codes[2] != http.StatusTooManyRequests {
+			t.Errorf("Got codes %v, want the third request limited", codes)
+		}
+	})
+
+	t.Run("error rate", func(t *testing.T) {
+		fake.SetBehavior(Behavior{ErrorRate: 0.5})
+		failed := 0
+		for i := 0; i < 100; i++ {
+			if code, _ := get(t, context.Background(), url); code == http.StatusServiceUnavailable {
+				failed++
+			}
+		}
+		if failed < 25 || failed > 75 {
+			t.Errorf("Got %d of 100 requests failed, want about half", failed)
+		}
+	})
+}
+
+func TestWalk(t *testing.T) {
+	first, second := NewWithSeed(7), NewWithSeed(7)
+	for i := 0; i < 10; i++ {
+		first.Walk(0.01)
+		second.Walk(0.01)
+	}
+	spot := first.Prices()["BTC-USD"]
+	if spot == 50000 || spot < 50000*0.9 || spot > 50000*1.1 {
+		t.Errorf("Got BTC-USD %f after 10 steps of 1%%", spot)
+	}
+	if spot != second.Prices()["BTC-USD"] {
+		t.Errorf("Walks with the same seed differ")
+	}
+}
@@ -0,0 +1,17 @@
+module github.com/kalynv/coinbase-pinger/e2e
+
+go 1.16
+
+require (
+	github.com/kalynv/coinbase-pinger/app v0.0.0
+	github.com/kalynv/coinbase-pinger/operator v0.0.0
+	k8s.io/api v0.22.6
+	k8s.io/apimachinery v0.22.6
+	k8s.io/client-go v0.22.6
+	sigs.k8s.io/controller-runtime v0.10.0
+)
+
+replace (
+	github.com/kalynv/coinbase-pinger/app => ../app
+	github.com/kalynv/coinbase-pinger/operator => ../operator
+)
@@ -0,0 +1,272 @@
+package e2e
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kalynv/coinbase-pinger/app/probe"
+	"github.com/kalynv/coinbase-pinger/e2e/fakecoinbase"
+	devorgv1 "github.com/kalynv/coinbase-pinger/operator/api/v1"
+	"github.com/kalynv/coinbase-pinger/operator/controllers"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/envtest"
+)
+
+const (
+	timeout  = 10 * time.Second
+	interval = 250 * time.Millisecond
+)
+
+var (
+	k8sClient client.Client
+	clientset kubernetes.Interface
+)
+
+// TestMain runs the operator against a local envtest API server. Pinger
+// pods are never scheduled there, so tests run the probe themselves and
+// report through the same sink the pinger uses.
+func TestMain(m *testing.M) {
+	testEnv := &envtest.Environment{
+		CRDDirectoryPaths:     []string{filepath.Join("..", "operator", "config", "crd", "bases")},
+		ErrorIfCRDPathMissing: true,
+	}
+	cfg, err := testEnv.Start()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "unable to start envtest:", err)
+		os.Exit(1)
+	}
+
+	code := func() int {
+		defer testEnv.Stop()
+		if err := devorgv1.AddToScheme(scheme.Scheme); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		if k8sClient, err = client.New(cfg, client.Options{Scheme: scheme.Scheme}); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		if clientset, err = kubernetes.NewForConfig(cfg); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+
+		mgr, err := ctrl.NewManager(cfg, ctrl.Options{
+			Scheme:             scheme.Scheme,
+			MetricsBindAddress: "0",
+			NewCache:           controllers.NewCache(cache.Options{}, nil),
+		})
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		err = (&controllers.CoinbasePingerReconciler{
+			Client:   mgr.GetClient(),
+			Scheme:   mgr.GetScheme(),
+			Recorder: mgr.GetEventRecorderFor("coinbasepinger-controller"),
+		}).SetupWithManager(mgr)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go func() {
+			if err := mgr.Start(ctx); err != nil {
+				fmt.Fprintln(os.Stderr, "manager stopped:", err)
+			}
+		}()
+
+		return m.Run()
+	}()
+	os.Exit(code)
+}
+
+func TestPingToStatus(t *testing.T) {
+	fake := fakecoinbase.New()
+	plain := httptest.NewServer(fake)
+	defer plain.Close()
+	tlsServer := httptest.NewTLSServer(fake)
+	defer tlsServer.Close()
+
+	amount, err := probe.ParseAssertion("json=data.amount")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		behavior   fakecoinbase.Behavior
+		baseURL    string
+		insecure   bool
+		timeout    time.Duration
+		wantType   string
+		wantStatus bool
+		wantReason string
+	}{
+		{
+			name:       "price served",
+			baseURL:    plain.URL,
+			wantType:   probe.ServiceOnline,
+			wantStatus: true,
+			wantReason: probe.PingSucceeded,
+		},
+		{
+			name:       "server error",
+			behavior:   fakecoinbase.Behavior{StatusCode: http.StatusServiceUnavailable},
+			baseURL:    plain.URL,
+			wantType:   probe.ServiceOnline,
+			wantReason: probe.AssertionFailed,
+		},
+		{
+			name:       "malformed JSON",
+			behavior:   fakecoinbase.Behavior{Malformed: true},
+			baseURL:    plain.URL,
+			wantType:   probe.ServiceOnline,
+			wantReason: probe.AssertionFailed,
+		},
+		{
+			name:       "slower than the timeout",
+			behavior:   fakecoinbase.Behavior{Latency: 2 * time.Second},
+			baseURL:    plain.URL,
+			timeout:    200 * time.Millisecond,
+			wantType:   probe.ServiceOffline,
+			wantReason: probe.PingFailed,
+		},
+		{
+			name:       "TLS without verification",
+			baseURL:    tlsServer.URL,
+			insecure:   true,
+			wantType:   probe.ServiceOnline,
+			wantStatus: true,
+			wantReason: probe.PingSucceeded,
+		},
+		{
+			name:       "TLS with an untrusted certificate",
+			baseURL:    tlsServer.URL,
+			wantType:   probe.ServiceOffline,
+			wantReason: probe.PingFailed,
+		},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			fake.SetBehavior(tt.behavior)
+
+			pinger := &devorgv1.CoinbasePinger{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      fmt.Sprintf("e2e-%d", i),
+					Namespace: "default",
+				},
+				Spec: devorgv1.CoinbasePingerSpec{Interval: "5m"},
+			}
+			if err := k8sClient.Create(ctx, pinger); err != nil {
+				t.Fatal(err)
+			}
+			defer k8sClient.Delete(ctx, pinger)
+
+			cronJob := waitForCronJob(t, ctx, pinger)
+			pod := runPingerPod(t, ctx, cronJob, probe.Request{
+				URL:        tt.baseURL + "/v2/prices/BTC-USD/buy",
+				Timeout:    tt.timeout,
+				Assertions: []probe.Assertion{{Kind: probe.StatusAssertion, Argument: "2xx"}, amount},
+			}, tt.insecure)
+
+			condition := waitForCondition(t, ctx, pinger)
+			if condition.Type != tt.wantType ||
+				condition.Status != tt.wantStatus ||
+				condition.Reason != tt.wantReason {
+				t.Errorf("Got condition %s/%v/%s from pod %s, want %s/%v/%s",
+					condition.Type, condition.Status, condition.Reason, pod.Name,
+					tt.wantType, tt.wantStatus, tt.wantReason)
+			}
+		})
+	}
+}
+
+// waitForCronJob returns the CronJob the operator created for the pinger.
+func waitForCronJob(t *testing.T, ctx context.Context, pinger *devorgv1.CoinbasePinger) *batchv1.CronJob {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		cronJobs := batchv1.CronJobList{}
+		if err := k8sClient.List(ctx, &cronJobs, client.InNamespace(pinger.Namespace)); err != nil {
+			t.Fatal(err)
+		}
+		for i := range cronJobs.Items {
+			if metav1.IsControlledBy(&cronJobs.Items[i], pinger) {
+				return &cronJobs.Items[i]
+			}
+		}
+		time.Sleep(interval)
+	}
+	t.Fatalf("No CronJob was created for CoinbasePinger %s", pinger.Name)
+	return nil
+}
+
+// runPingerPod simulates the CronJob running a pinger pod: it creates the
+// pod from the job template, probes and reports to the pod like the pinger
+// does with --output=k8s-pod.
+func runPingerPod(
+	t *testing.T,
+	ctx context.Context,
+	cronJob *batchv1.CronJob,
+	request probe.Request,
+	insecure bool,
+) *corev1.Pod {
+	t.Helper()
+	template := cronJob.Spec.JobTemplate.Spec.Template
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: cronJob.Name + "-",
+			Namespace:    cronJob.Namespace,
+			Labels:       template.Labels,
+		},
+		Spec: template.Spec,
+	}
+	if err := k8sClient.Create(ctx, pod); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := probe.NewHTTPProber(insecure, nil).Probe(ctx, request)
+	if err != nil {
+		t.Logf("probe failed: %v", err)
+	}
+	sink := probe.PodSink{Clientset: clientset, Namespace: pod.Namespace, Name: pod.Name}
+	if err := sink.Report(ctx, result); err != nil {
+		t.Fatal(err)
+	}
+	return pod
+}
+
+// waitForCondition returns the ping result the operator collected into the
+// pinger status.
+func waitForCondition(t *testing.T, ctx context.Context, pinger *devorgv1.CoinbasePinger) devorgv1.Condition {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if err := k8sClient.Get(ctx, client.ObjectKeyFromObject(pinger), pinger); err != nil {
+			t.Fatal(err)
+		}
+		if len(pinger.Status.Conditions) > 0 {
+			return pinger.Status.Conditions[0]
+		}
+		time.Sleep(interval)
+	}
+	t.Fatalf("CoinbasePinger %s status has no conditions", pinger.Name)
+	return devorgv1.Condition{}
+}
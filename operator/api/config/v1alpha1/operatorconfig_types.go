@@ -0,0 +1,93 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	cfg "sigs.k8s.io/controller-runtime/pkg/config/v1alpha1"
+)
+
+// PingerDefaults configures the pinger pods. Empty fields fall back to the
+// operator defaults.
+type PingerDefaults struct {
+	// Image of the pinger container.
+	Image string `json:"image,omitempty"`
+	// ServiceAccountName the pinger pods run as. When empty every pinger
+	// gets its own ServiceAccount with the permissions it needs.
+	ServiceAccountName string `json:"serviceAccountName,omitempty"`
+	// BaseURL of the Coinbase API, used unless the pinger sets its own.
+	BaseURL string `json:"baseURL,omitempty"`
+	// Resources of the pinger container.
+	Resources corev1.ResourceRequirements `json:"resources,omitempty"`
+}
+
+// EgressConfig limits where pingers may connect to.
+type EgressConfig struct {
+	// AllowedHosts are host patterns pingers may call, `*.example.org`
+	// matches the subdomains of example.org.
+	AllowedHosts []string `json:"allowedHosts,omitempty"`
+	// AllowedCIDRs are the IP ranges pingers may call by address.
+	AllowedCIDRs []string `json:"allowedCIDRs,omitempty"`
+	// NetworkPolicy creates a NetworkPolicy per pinger, which allows egress
+	// only to DNS, the API server and the addresses of the target host.
+	NetworkPolicy bool `json:"networkPolicy,omitempty"`
+	// DNSCIDRs pinger pods may query DNS at besides the kube-dns pods in
+	// kube-system, like 169.254.20.10/32 for NodeLocal DNSCache.
+	DNSCIDRs []string `json:"dnsCIDRs,omitempty"`
+	// APIServerCIDRs the pinger pods report their results to. Defaults to
+	// the endpoints of the kubernetes Service.
+	APIServerCIDRs []string `json:"apiServerCIDRs,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// OperatorConfig is the Schema for the operator configuration file. It
+// extends the controller manager configuration with pinger defaults.
+type OperatorConfig struct {
+	metav1.TypeMeta `json:",inline"`
+
+	// ControllerManagerConfigurationSpec configures the manager: health,
+	// metrics, webhook and leader election.
+	cfg.ControllerManagerConfigurationSpec `json:",inline"`
+
+	// Pinger holds the defaults of pinger pods.
+	Pinger PingerDefaults `json:"pinger,omitempty"`
+	// NamespaceOverrides replace the pinger defaults field by field for
+	// pingers in the namespace.
+	NamespaceOverrides map[string]PingerDefaults `json:"namespaceOverrides,omitempty"`
+	// AllowedBaseURLDomains restricts the hosts pingers may call, a domain
+	// also allows its subdomains. All hosts are allowed when empty.
+	AllowedBaseURLDomains []string `json:"allowedBaseURLDomains,omitempty"`
+	// Egress restricts the targets of pingers further. When any allowlist
+	// is set, base URLs must match one of them.
+	Egress EgressConfig `json:"egress,omitempty"`
+	// RequeueAfter is the delay before a failed reconcile is retried.
+	RequeueAfter metav1.Duration `json:"requeueAfter,omitempty"`
+	// MinInterval is the shortest interval pingers may ping at.
+	MinInterval metav1.Duration `json:"minInterval,omitempty"`
+}
+
+// Complete returns the manager configuration, see
+// sigs.k8s.io/controller-runtime/pkg/config.ControllerManagerConfiguration.
+func (c *OperatorConfig) Complete() (cfg.ControllerManagerConfigurationSpec, error) {
+	return c.ControllerManagerConfigurationSpec, nil
+}
+
+func init() {
+	SchemeBuilder.Register(&OperatorConfig{})
+}
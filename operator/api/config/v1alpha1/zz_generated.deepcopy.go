@@ -0,0 +1,117 @@
+// +build !ignore_autogenerated
+
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by controller-gen. DO NOT EDIT.
+
+package v1alpha1
+
+import (
+	runtime "k8s.io/apimachinery/pkg/runtime"
+)
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EgressConfig) DeepCopyInto(out *EgressConfig) {
+	*out = *in
+	if in.AllowedHosts != nil {
+		in, out := &in.AllowedHosts, &out.AllowedHosts
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.AllowedCIDRs != nil {
+		in, out := &in.AllowedCIDRs, &out.AllowedCIDRs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.DNSCIDRs != nil {
+		in, out := &in.DNSCIDRs, &out.DNSCIDRs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.APIServerCIDRs != nil {
+		in, out := &in.APIServerCIDRs, &out.APIServerCIDRs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EgressConfig.
+func (in *EgressConfig) DeepCopy() *EgressConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(EgressConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OperatorConfig) DeepCopyInto(out *OperatorConfig) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ControllerManagerConfigurationSpec.DeepCopyInto(&out.ControllerManagerConfigurationSpec)
+	in.Pinger.DeepCopyInto(&out.Pinger)
+	if in.NamespaceOverrides != nil {
+		in, out := &in.NamespaceOverrides, &out.NamespaceOverrides
+		*out = make(map[string]PingerDefaults, len(*in))
+		for key, val := range *in {
+			(*out)[key] = *val.DeepCopy()
+		}
+	}
+	if in.AllowedBaseURLDomains != nil {
+		in, out := &in.AllowedBaseURLDomains, &out.AllowedBaseURLDomains
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	in.Egress.DeepCopyInto(&out.Egress)
+	out.RequeueAfter = in.RequeueAfter
+	out.MinInterval = in.MinInterval
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OperatorConfig.
+func (in *OperatorConfig) DeepCopy() *OperatorConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(OperatorConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *OperatorConfig) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PingerDefaults) DeepCopyInto(out *PingerDefaults) {
+	*out = *in
+	in.Resources.DeepCopyInto(&out.Resources)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PingerDefaults.
+func (in *PingerDefaults) DeepCopy() *PingerDefaults {
+	if in == nil {
+		return nil
+	}
+	out := new(PingerDefaults)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -17,18 +17,264 @@ limitations under the License.
 package v1
 
 import (
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
+// PingNowAnnotation requests an immediate one-off ping. Set it to a new
+// nonce value to trigger a ping outside of the regular schedule.
+const PingNowAnnotation string = "batch.dev.org/ping-now"
+
+// Labels the operator sets on the pinger pods to find the pods of a pinger.
+const (
+	CRD_UID       string = "webapp-pinger"
+	CRD_NAME      string = "notify-name"
+	CRD_NAMESPACE string = "notify-namespace"
+)
+
+// Labels and annotations the pinger pods report their ping result with.
+const (
+	TypeLabel                  string = "type"
+	StatusLabel                string = "status"
+	ReasonLabel                string = "reason"
+	MessageAnnotation          string = "message"
+	PingTimeAnnotation         string = "ping-time"
+	FailedAssertionsAnnotation string = "failed-assertions"
+	FailedHopAnnotation        string = "failed-hop"
+)
+
+const (
+	// ServiceUnknown is the Condition type of pings which did not reach the
+	// service at all.
+	ServiceUnknown string = "ServiceUnknown"
+	// ProbeInfrastructureFailure is the Condition reason of pings which did
+	// not run because the pinger pod itself failed, for example on an image
+	// pull error, OOM kill, eviction or deadline.
+	ProbeInfrastructureFailure string = "ProbeInfrastructureFailure"
+	// PingerRejected is the Condition type recorded when the spec or the
+	// operator config rejects the pinger. Its CronJob is suspended until the
+	// pinger is accepted again, the reason tells why, like BaseURLNotAllowed.
+	PingerRejected string = "PingerRejected"
+)
+
+// ProbeType is the protocol the pinger probes with.
+// +kubebuilder:validation:Enum=http;tcp;dns;tls;grpc;websocket
+type ProbeType string
+
+const (
+	HTTPProbe ProbeType = "http"
+	TCPProbe  ProbeType = "tcp"
+	DNSProbe  ProbeType = "dns"
+	TLSProbe  ProbeType = "tls"
+	GRPCProbe ProbeType = "grpc"
+	// WebSocketProbe subscribes to a feed like the Coinbase Exchange ticker
+	// channel and checks that messages keep coming and are fresh.
+	WebSocketProbe ProbeType = "websocket"
+)
+
+// DefaultEndpoint is the path HTTP probes of pingers without an endpoint
+// request.
+const DefaultEndpoint string = "/prices/BTC-USD/buy"
+
+// ConcurrencyPolicy describes how the pinger treats a run which is due
+// while the previous one is still running. It mirrors the CronJob one.
+// +kubebuilder:validation:Enum=Allow;Forbid;Replace
+type ConcurrencyPolicy string
+
 // CoinbasePingerSpec defines the desired state of CoinbasePinger
 type CoinbasePingerSpec struct {
+	// Endpoint is the path HTTP probes request, relative to the base URL.
+	// Defaults to /prices/BTC-USD/buy when empty.
 	Endpoint string `json:"endpoint"`
 	Interval string `json:"interval"`
+
+	// BaseURL of the Coinbase API. Defaults to the one of the operator
+	// config, its host must be one of the allowed base URL domains.
+	// +optional
+	BaseURL string `json:"baseURL,omitempty"`
+	// Probe selects a protocol other than HTTP. HTTP probes ping the
+	// endpoint of the base URL.
+	// +optional
+	Probe *ProbeSpec `json:"probe,omitempty"`
+
+	// Proxy HTTP and websocket probes reach their target through. Without
+	// it pinger pods still honor proxy environment variables set on them
+	// by other means.
+	// +optional
+	Proxy *ProxySpec `json:"proxy,omitempty"`
+
+	// SuccessfulJobsHistoryLimit is the number of finished pinger Jobs to
+	// keep. Ping results survive their removal in the status.
+	// +kubebuilder:default=3
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	SuccessfulJobsHistoryLimit *int32 `json:"successfulJobsHistoryLimit,omitempty"`
+	// FailedJobsHistoryLimit is the number of failed pinger Jobs to keep.
+	// +kubebuilder:default=1
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	FailedJobsHistoryLimit *int32 `json:"failedJobsHistoryLimit,omitempty"`
+	// StartingDeadlineSeconds is how late a missed ping may still start.
+	// +kubebuilder:default=60
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	StartingDeadlineSeconds *int64 `json:"startingDeadlineSeconds,omitempty"`
+	// ActiveDeadlineSeconds bounds a single run, so a hung pinger does not
+	// block the following ones.
+	// +kubebuilder:default=120
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	ActiveDeadlineSeconds *int64 `json:"activeDeadlineSeconds,omitempty"`
+	// BackoffLimit is the number of retries of a failed run. The next
+	// scheduled run pings again anyway.
+	// +kubebuilder:default=0
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	BackoffLimit *int32 `json:"backoffLimit,omitempty"`
+	// TTLSecondsAfterFinished removes finished Jobs, including ping-now
+	// ones which are not subject to the history limits.
+	// +kubebuilder:default=86400
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	TTLSecondsAfterFinished *int32 `json:"ttlSecondsAfterFinished,omitempty"`
+	// ConcurrencyPolicy of the pinger CronJob.
+	// +kubebuilder:default=Forbid
+	// +optional
+	ConcurrencyPolicy ConcurrencyPolicy `json:"concurrencyPolicy,omitempty"`
+
+	// Pinger pods pass the restricted Pod Security Standard by default.
+	// The fields below replace the defaults as a whole, set them only to
+	// relax them explicitly.
+
+	// PodSecurityContext replaces the default non-root, RuntimeDefault
+	// seccomp pod security context.
+	// +optional
+	PodSecurityContext *corev1.PodSecurityContext `json:"podSecurityContext,omitempty"`
+	// SecurityContext replaces the default security context of the pinger
+	// container, which drops all capabilities, forbids privilege escalation
+	// and mounts the root filesystem read-only.
+	// +optional
+	SecurityContext *corev1.SecurityContext `json:"securityContext,omitempty"`
+	// Resources replaces the requests and limits of the pinger container
+	// the operator config defaults to.
+	// +optional
+	Resources *corev1.ResourceRequirements `json:"resources,omitempty"`
+}
+
+// ProbeSpec configures the probe of the pinger. Settings of the other
+// probe types are ignored.
+type ProbeSpec struct {
+	// Type of the probe.
+	// +kubebuilder:default=http
+	// +optional
+	Type ProbeType `json:"type,omitempty"`
+	// Target of probes other than http: host:port for tcp, tls and grpc,
+	// where tls defaults to port 443, a name for dns and a ws or wss feed
+	// URL for websocket. Hosts pinger pods
+	// connect to, which are the dns server for dns, must be allowed like
+	// the one of the base URL.
+	// +optional
+	Target string `json:"target,omitempty"`
+	// +optional
+	DNS *DNSProbeSpec `json:"dns,omitempty"`
+	// +optional
+	TLS *TLSProbeSpec `json:"tls,omitempty"`
+	// +optional
+	GRPC *GRPCProbeSpec `json:"grpc,omitempty"`
+	// +optional
+	WebSocket *WebSocketProbeSpec `json:"websocket,omitempty"`
+}
+
+// DNSProbeSpec configures dns probes.
+type DNSProbeSpec struct {
+	// RecordType to look up.
+	// +kubebuilder:validation:Enum=A;AAAA;CNAME;MX;NS;TXT
+	// +kubebuilder:default=A
+	// +optional
+	RecordType string `json:"recordType,omitempty"`
+	// Server to ask, as host or host:port. The cluster DNS when empty.
+	// +optional
+	Server string `json:"server,omitempty"`
+}
+
+// TLSProbeSpec configures tls probes, which verify the certificate of the
+// target.
+type TLSProbeSpec struct {
+	// ServerName the certificate is verified for, the target host when
+	// empty.
+	// +optional
+	ServerName string `json:"serverName,omitempty"`
+	// MinValidity is how long the certificate must remain valid at least.
+	// +optional
+	MinValidity *metav1.Duration `json:"minValidity,omitempty"`
+}
+
+// GRPCProbeSpec configures grpc probes of the standard health service.
+type GRPCProbeSpec struct {
+	// Service to check the health of, the server as a whole when empty.
+	// +optional
+	Service string `json:"service,omitempty"`
+	// TLS connects with TLS instead of plaintext.
+	// +optional
+	TLS bool `json:"tls,omitempty"`
+}
+
+// WebSocketProbeSpec configures websocket probes. Only messages carrying
+// a server timestamp count, a feed which sends fewer of them in time or
+// lags behind fails the ping.
+type WebSocketProbeSpec struct {
+	// Subscribe is the message sent once connected, for example
+	// {"type":"subscribe","product_ids":["BTC-USD"],"channels":["ticker"]}.
+	// +optional
+	Subscribe string `json:"subscribe,omitempty"`
+	// Messages is how many messages must arrive.
+	// +kubebuilder:default=1
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	Messages *int32 `json:"messages,omitempty"`
+	// Within is how long after subscribing the messages must arrive.
+	// +optional
+	Within *metav1.Duration `json:"within,omitempty"`
+	// MaxStaleness is the largest age of a message against its server
+	// timestamp. It is not checked when unset.
+	// +optional
+	MaxStaleness *metav1.Duration `json:"maxStaleness,omitempty"`
+	// TimeField is the JSON path of the server timestamp.
+	// +kubebuilder:default=time
+	// +optional
+	TimeField string `json:"timeField,omitempty"`
+}
+
+// ProxySpec configures the proxy of the pinger.
+type ProxySpec struct {
+	// URL of the proxy, without credentials. Its host must be allowed like
+	// the one of the base URL.
+	// +kubebuilder:validation:Pattern=`^(http|https|socks5)://`
+	URL string `json:"url"`
+	// NoProxy lists hosts, domains and CIDRs reached directly, in the
+	// NO_PROXY environment variable format.
+	// +optional
+	NoProxy []string `json:"noProxy,omitempty"`
+	// CredentialsSecretName is a Secret in the namespace of the pinger with
+	// the username and password of the proxy.
+	// +optional
+	CredentialsSecretName string `json:"credentialsSecretName,omitempty"`
 }
 
 // CoinbasePingerStatus defines the observed state of CoinbasePinger
 type CoinbasePingerStatus struct {
-	Conditions []Condition `json:"conditions"`
+	// Conditions is the ping history, oldest first. Results are kept after
+	// their pods are removed, up to a limit.
+	Conditions []Condition `json:"conditions,omitempty"`
+	// ObservedGeneration is the generation of the spec the CronJob was
+	// last reconciled with.
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// LastHandledPingNow is the last ping-now annotation nonce a one-off
+	// Job was created for.
+	LastHandledPingNow string `json:"lastHandledPingNow,omitempty"`
+	// MissingPermissions lists permissions the ServiceAccount of the pinger
+	// pods lacks to report results, like `patch pods`.
+	MissingPermissions []string `json:"missingPermissions,omitempty"`
 }
 
 // Condition contains webping result fetched from a pod metadata
@@ -38,6 +284,14 @@ type Condition struct {
 	Reason   string      `json:"reason"`
 	Message  string      `json:"message"`
 	PingTime metav1.Time `json:"pingTime,omitempty"`
+	// FailedAssertions lists response assertions the ping did not satisfy.
+	FailedAssertions []string `json:"failedAssertions,omitempty"`
+	// FailedHop tells whether the proxy or the target failed the ping.
+	// +kubebuilder:validation:Enum=proxy;target
+	// +optional
+	FailedHop string `json:"failedHop,omitempty"`
+	// Source is the name of the pod which reported the result.
+	Source string `json:"source,omitempty"`
 }
 
 //+kubebuilder:object:root=true
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// runExplainFailure prints failed ping results with their reasons and
+// snippets of the response body.
+func runExplainFailure(opts options, args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: explain-failure <name>")
+	}
+	s, err := newSession(opts)
+	if err != nil {
+		return err
+	}
+	pinger, err := s.getPinger(context.Background(), args[0])
+	if err != nil {
+		return err
+	}
+
+	failed := 0
+	conditions := sortedConditions(pinger.Status.Conditions)
+	for i := range conditions {
+		condition := &conditions[i]
+		if condition.Status {
+			continue
+		}
+		failed++
+		fmt.Printf("%s  %s/%s\n", formatPingTime(condition), condition.Type, condition.Reason)
+		if condition.FailedHop != "" {
+			fmt.Printf("  failed hop: %s\n", condition.FailedHop)
+		}
+		for _, failure := range condition.FailedAssertions {
+			fmt.Printf("  assertion failed: %s\n", failure)
+		}
+		if condition.Message != "" {
+			fmt.Printf("  body: %s\n", snippet(condition.Message, snippetLength))
+		}
+	}
+
+	if failed == 0 {
+		fmt.Printf("coinbasepinger/%s has no failed pings in its history\n", pinger.Name)
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+)
+
+// runHistory prints retained ping results of a CoinbasePinger.
+func runHistory(opts options, args []string) error {
+	name, output, err := parseHistoryArgs(args)
+	if err != nil {
+		return err
+	}
+
+	s, err := newSession(opts)
+	if err != nil {
+		return err
+	}
+	pinger, err := s.getPinger(context.Background(), name)
+	if err != nil {
+		return err
+	}
+	conditions := sortedConditions(pinger.Status.Conditions)
+
+	if output == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(conditions)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "PING TIME\tTYPE\tSTATUS\tREASON\tMESSAGE")
+	for i := range conditions {
+		condition := &conditions[i]
+		fmt.Fprintf(
+			w,
+			"%s\t%s\t%v\t%s\t%s\n",
+			formatPingTime(condition),
+			condition.Type,
+			condition.Status,
+			condition.Reason,
+			snippet(condition.Message, 60),
+		)
+	}
+	return w.Flush()
+}
+
+// parseHistoryArgs returns the pinger name and the output format. Flags may
+// come before or after the name, flag.Parse stops at the first argument so
+// the rest is parsed again.
+func parseHistoryArgs(args []string) (name string, output string, err error) {
+	flags := flag.NewFlagSet("history", flag.ContinueOnError)
+	flags.StringVar(&output, "o", "table", "Output format: table or json.")
+	var names []string
+	for {
+		if err := flags.Parse(args); err != nil {
+			return "", "", err
+		}
+		if flags.NArg() == 0 {
+			break
+		}
+		names = append(names, flags.Arg(0))
+		args = flags.Args()[1:]
+	}
+	if len(names) != 1 {
+		return "", "", errors.New("usage: history <name> [-o table|json]")
+	}
+	if output != "table" && output != "json" {
+		return "", "", fmt.Errorf("unknown output format %q", output)
+	}
+	return names[0], output, nil
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"testing"
+)
+
+func Test_parseHistoryArgs(t *testing.T) {
+	tests := []struct {
+		args       []string
+		wantName   string
+		wantOutput string
+		wantError  string
+	}{
+		{args: []string{"btc"}, wantName: "btc", wantOutput: "table"},
+		{args: []string{"btc", "-o", "json"}, wantName: "btc", wantOutput: "json"},
+		{args: []string{"-o", "json", "btc"}, wantName: "btc", wantOutput: "json"},
+		{args: []string{"-o=json", "btc"}, wantName: "btc", wantOutput: "json"},
+		{args: []string{}, wantError: "usage: history <name> [-o table|json]"},
+		{args: []string{"btc", "eth"}, wantError: "usage: history <name> [-o table|json]"},
+		{args: []string{"btc", "-o", "yaml"}, wantError: `unknown output format "yaml"`},
+	}
+	for _, tt := range tests {
+		name, output, err := parseHistoryArgs(tt.args)
+		gotError := ""
+		if err != nil {
+			gotError = err.Error()
+		}
+		if name != tt.wantName || output != tt.wantOutput || gotError != tt.wantError {
+			t.Errorf(
+				"Got [%s] [%s] and error [%s] for %v, want [%s] [%s] and error [%s]",
+				name, output, gotError, tt.args, tt.wantName, tt.wantOutput, tt.wantError,
+			)
+		}
+	}
+}
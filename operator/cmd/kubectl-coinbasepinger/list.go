@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	devorgv1 "github.com/kalynv/coinbase-pinger/operator/api/v1"
+)
+
+// runList prints a health summary of every CoinbasePinger in the namespace.
+func runList(opts options, args []string) error {
+	if len(args) != 0 {
+		return errors.New("usage: list")
+	}
+	s, err := newSession(opts)
+	if err != nil {
+		return err
+	}
+
+	list := devorgv1.CoinbasePingerList{}
+	err = s.client.List(context.Background(), &list, client.InNamespace(s.namespace))
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tTARGET\tINTERVAL\tHEALTH\tLAST PING\tSUCCESS")
+	for _, pinger := range list.Items {
+		state, last := health(pinger.Status.Conditions)
+		fmt.Fprintf(
+			w,
+			"%s\t%s\t%s\t%s\t%s\t%s\n",
+			pinger.Name,
+			pingedTarget(pinger),
+			pinger.Spec.Interval,
+			state,
+			formatPingTime(last),
+			successRatio(pinger.Status.Conditions),
+		)
+	}
+	return w.Flush()
+}
+
+// pingedTarget returns what the pinger pods probe: the endpoint for HTTP
+// probes, the probe target otherwise.
+func pingedTarget(pinger devorgv1.CoinbasePinger) string {
+	probe := pinger.Spec.Probe
+	if probe != nil && probe.Type != "" && probe.Type != devorgv1.HTTPProbe {
+		return probe.Target
+	}
+	if pinger.Spec.Endpoint == "" {
+		return devorgv1.DefaultEndpoint
+	}
+	return pinger.Spec.Endpoint
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"testing"
+
+	devorgv1 "github.com/kalynv/coinbase-pinger/operator/api/v1"
+)
+
+func Test_pingedTarget(t *testing.T) {
+	tests := []struct {
+		name string
+		spec devorgv1.CoinbasePingerSpec
+		want string
+	}{
+		{"default endpoint", devorgv1.CoinbasePingerSpec{}, "/prices/BTC-USD/buy"},
+		{"endpoint", devorgv1.CoinbasePingerSpec{Endpoint: "/prices/ETH-USD/spot"}, "/prices/ETH-USD/spot"},
+		{
+			"tcp probe",
+			devorgv1.CoinbasePingerSpec{
+				Endpoint: "/prices/ETH-USD/spot",
+				Probe:    &devorgv1.ProbeSpec{Type: devorgv1.TCPProbe, Target: "gateway.internal:5432"},
+			},
+			"gateway.internal:5432",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := pingedTarget(devorgv1.CoinbasePinger{Spec: tt.spec})
+			if got != tt.want {
+				t.Errorf("Got [%s], want [%s]", got, tt.want)
+			}
+		})
+	}
+}
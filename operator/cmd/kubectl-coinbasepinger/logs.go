@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	devorgv1 "github.com/kalynv/coinbase-pinger/operator/api/v1"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// runLogs streams the log of the most recently created pinger pod.
+func runLogs(opts options, args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: logs <name>")
+	}
+	s, err := newSession(opts)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	pinger, err := s.getPinger(ctx, args[0])
+	if err != nil {
+		return err
+	}
+
+	pods := corev1.PodList{}
+	err = s.client.List(
+		ctx,
+		&pods,
+		client.InNamespace(s.namespace),
+		client.MatchingLabels{devorgv1.CRD_UID: string(pinger.UID)},
+	)
+	if err != nil {
+		return err
+	}
+	if len(pods.Items) == 0 {
+		return fmt.Errorf("no pinger pods found for coinbasepinger/%s", pinger.Name)
+	}
+
+	last := pods.Items[0]
+	for _, pod := range pods.Items[1:] {
+		if last.CreationTimestamp.Before(&pod.CreationTimestamp) {
+			last = pod
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "pod/%s\n", last.Name)
+	stream, err := s.clientset.
+		CoreV1().
+		Pods(last.Namespace).
+		GetLogs(last.Name, &corev1.PodLogOptions{}).
+		Stream(ctx)
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+
+	_, err = io.Copy(os.Stdout, stream)
+	return err
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/kubernetes"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	devorgv1 "github.com/kalynv/coinbase-pinger/operator/api/v1"
+)
+
+const usage = `kubectl coinbasepinger inspects and controls CoinbasePinger resources.
+
+Usage:
+  kubectl coinbasepinger [--kubeconfig PATH] [-n NAMESPACE] <command> [args]
+
+Commands:
+  list                     show health, last ping and success ratio of pingers
+  history <name> [-o FMT]  show retained ping results, FMT is table or json
+  trigger <name>           run a ping now, outside of the regular schedule
+  logs <name>              print the log of the last pinger pod
+  explain-failure <name>   show failed ping results with reasons and body snippets
+`
+
+var scheme = runtime.NewScheme()
+
+func init() {
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(devorgv1.AddToScheme(scheme))
+}
+
+// options holds the global flags shared by all commands.
+type options struct {
+	kubeconfig string
+	namespace  string
+}
+
+func main() {
+	opts := options{}
+	flags := flag.NewFlagSet("kubectl-coinbasepinger", flag.ExitOnError)
+	flags.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+	flags.StringVar(&opts.kubeconfig, "kubeconfig", "", "Path to the kubeconfig file.")
+	flags.StringVar(&opts.namespace, "namespace", "", "Namespace of CoinbasePinger resources.")
+	flags.StringVar(&opts.namespace, "n", "", "Namespace of CoinbasePinger resources (shorthand).")
+	_ = flags.Parse(os.Args[1:])
+
+	args := flags.Args()
+	if len(args) == 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	var err error
+	switch command := args[0]; command {
+	case "list":
+		err = runList(opts, args[1:])
+	case "history":
+		err = runHistory(opts, args[1:])
+	case "trigger":
+		err = runTrigger(opts, args[1:])
+	case "logs":
+		err = runLogs(opts, args[1:])
+	case "explain-failure":
+		err = runExplainFailure(opts, args[1:])
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n", command)
+		flags.Usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+}
+
+// session holds the clients and the namespace commands operate on.
+type session struct {
+	client    client.Client
+	clientset kubernetes.Interface
+	namespace string
+}
+
+// newSession builds clients and resolves the namespace from flags or the
+// current kubeconfig context.
+func newSession(opts options) (*session, error) {
+	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+	loadingRules.ExplicitPath = opts.kubeconfig
+	clientConfig := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+		loadingRules,
+		&clientcmd.ConfigOverrides{},
+	)
+
+	config, err := clientConfig.ClientConfig()
+	if err != nil {
+		return nil, err
+	}
+	namespace := opts.namespace
+	if namespace == "" {
+		namespace, _, err = clientConfig.Namespace()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	c, err := client.New(config, client.Options{Scheme: scheme})
+	if err != nil {
+		return nil, err
+	}
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	return &session{client: c, clientset: clientset, namespace: namespace}, nil
+}
+
+// getPinger fetches the named CoinbasePinger from the session namespace.
+func (s *session) getPinger(ctx context.Context, name string) (*devorgv1.CoinbasePinger, error) {
+	pinger := &devorgv1.CoinbasePinger{}
+	key := client.ObjectKey{Namespace: s.namespace, Name: name}
+	err := s.client.Get(ctx, key, pinger)
+	return pinger, err
+}
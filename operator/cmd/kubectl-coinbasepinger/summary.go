@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	devorgv1 "github.com/kalynv/coinbase-pinger/operator/api/v1"
+)
+
+const (
+	healthHealthy      = "Healthy"
+	healthFailing      = "Failing"
+	healthProbeFailing = "ProbeFailing"
+	healthUnknown      = "Unknown"
+
+	snippetLength = 120
+)
+
+// sortedConditions returns conditions ordered from the oldest ping to the
+// newest one.
+func sortedConditions(conditions []devorgv1.Condition) []devorgv1.Condition {
+	sorted := make([]devorgv1.Condition, len(conditions))
+	copy(sorted, conditions)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].PingTime.Before(&sorted[j].PingTime)
+	})
+	return sorted
+}
+
+// health reports the pinger health from the newest ping result. Failures of
+// the pinger pod itself are told apart from failures of the service.
+func health(conditions []devorgv1.Condition) (state string, last *devorgv1.Condition) {
+	if len(conditions) == 0 {
+		return healthUnknown, nil
+	}
+	sorted := sortedConditions(conditions)
+	last = &sorted[len(sorted)-1]
+	switch {
+	case last.Status:
+		return healthHealthy, last
+	case last.Reason == devorgv1.ProbeInfrastructureFailure:
+		return healthProbeFailing, last
+	}
+	return healthFailing, last
+}
+
+// successRatio formats succeeded pings against retained pings which reached
+// the service, infrastructure failures are not counted.
+func successRatio(conditions []devorgv1.Condition) string {
+	succeeded, total := 0, 0
+	for _, condition := range conditions {
+		if condition.Reason == devorgv1.ProbeInfrastructureFailure {
+			continue
+		}
+		total++
+		if condition.Status {
+			succeeded++
+		}
+	}
+	if total == 0 {
+		return "-"
+	}
+	return fmt.Sprintf(
+		"%d/%d (%d%%)",
+		succeeded,
+		total,
+		succeeded*100/total,
+	)
+}
+
+// snippet flattens the message to one line and truncates it.
+func snippet(message string, length int) string {
+	message = strings.Join(strings.Fields(message), " ")
+	if len(message) <= length {
+		return message
+	}
+	return message[:length] + "..."
+}
+
+func formatPingTime(condition *devorgv1.Condition) string {
+	if condition == nil || condition.PingTime.IsZero() {
+		return "-"
+	}
+	return condition.PingTime.UTC().Format("2006-01-02T15:04:05Z")
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	devorgv1 "github.com/kalynv/coinbase-pinger/operator/api/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func Test_health(t *testing.T) {
+	now := time.Now()
+	older := devorgv1.Condition{Status: true, PingTime: metav1.NewTime(now.Add(-time.Minute))}
+	newer := devorgv1.Condition{Status: false, PingTime: metav1.NewTime(now)}
+	broken := devorgv1.Condition{
+		Reason:   devorgv1.ProbeInfrastructureFailure,
+		PingTime: metav1.NewTime(now.Add(time.Minute)),
+	}
+
+	tests := []struct {
+		name       string
+		conditions []devorgv1.Condition
+		wantState  string
+		wantRatio  string
+	}{
+		{
+			name:      "no results",
+			wantState: healthUnknown,
+			wantRatio: "-",
+		},
+		{
+			name:       "newest ping failed",
+			conditions: []devorgv1.Condition{newer, older},
+			wantState:  healthFailing,
+			wantRatio:  "1/2 (50%)",
+		},
+		{
+			name:       "newest pinger pod failed",
+			conditions: []devorgv1.Condition{older, broken},
+			wantState:  healthProbeFailing,
+			wantRatio:  "1/1 (100%)",
+		},
+		{
+			name:       "newest ping succeeded",
+			conditions: []devorgv1.Condition{older},
+			wantState:  healthHealthy,
+			wantRatio:  "1/1 (100%)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state, _ := health(tt.conditions)
+			if state != tt.wantState {
+				t.Errorf("Got health [%s], want [%s]", state, tt.wantState)
+			}
+			ratio := successRatio(tt.conditions)
+			if ratio != tt.wantRatio {
+				t.Errorf("Got ratio [%s], want [%s]", ratio, tt.wantRatio)
+			}
+		})
+	}
+}
+
+func Test_snippet(t *testing.T) {
+	got := snippet("{\n  \"data\": 1\n}", 8)
+	want := "{ \"data\"..."
+	if got != want {
+		t.Errorf("Got [%s], want [%s]", got, want)
+	}
+}
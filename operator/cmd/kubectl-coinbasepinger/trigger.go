@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	devorgv1 "github.com/kalynv/coinbase-pinger/operator/api/v1"
+)
+
+// runTrigger sets a fresh ping-now nonce, so the operator runs a one-off ping.
+func runTrigger(opts options, args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: trigger <name>")
+	}
+	s, err := newSession(opts)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	pinger, err := s.getPinger(ctx, args[0])
+	if err != nil {
+		return err
+	}
+
+	nonce := strconv.FormatInt(time.Now().UnixNano(), 10)
+	patch := client.MergeFrom(pinger.DeepCopy())
+	annotations := pinger.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[devorgv1.PingNowAnnotation] = nonce
+	pinger.SetAnnotations(annotations)
+	if err := s.client.Patch(ctx, pinger, patch); err != nil {
+		return err
+	}
+
+	fmt.Printf("coinbasepinger/%s triggered (nonce %s)\n", pinger.Name, nonce)
+	return nil
+}
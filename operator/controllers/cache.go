@@ -0,0 +1,118 @@
+package controllers
+
+import (
+	devorgv1 "github.com/kalynv/coinbase-pinger/operator/api/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+)
+
+// podPingerKey indexes pinger pods by the UID of their CoinbasePinger.
+const podPingerKey = ".metadata.labels." + devorgv1.CRD_UID
+
+// NewCache returns the manager cache builder. Pods, Jobs and the RBAC and
+// NetworkPolicy objects of pingers are only cached when they carry the
+// devorgv1.CRD_UID label, so memory use does not grow with the number of unrelated
+// objects in the cluster. With namespaces the cache only watches those, so
+// namespaced Roles are enough for the operator.
+func NewCache(options cache.Options, namespaces []string) cache.NewCacheFunc {
+	requirement, err := labels.NewRequirement(devorgv1.CRD_UID, selection.Exists, nil)
+	if err != nil {
+		panic(err)
+	}
+	selector := labels.NewSelector().Add(*requirement)
+	selectors := cache.SelectorsByObject{
+		&corev1.Pod{}:                 {Label: selector},
+		&batchv1.Job{}:                {Label: selector},
+		&corev1.ServiceAccount{}:      {Label: selector},
+		&rbacv1.Role{}:                {Label: selector},
+		&rbacv1.RoleBinding{}:         {Label: selector},
+		&networkingv1.NetworkPolicy{}: {Label: selector},
+	}
+	for obj, objSelector := range options.SelectorsByObject {
+		selectors[obj] = objSelector
+	}
+	options.SelectorsByObject = selectors
+
+	switch len(namespaces) {
+	case 0:
+		return cache.BuilderWithOptions(options)
+	case 1:
+		options.Namespace = namespaces[0]
+		return cache.BuilderWithOptions(options)
+	}
+	multiNamespaced := cache.MultiNamespacedCacheBuilder(namespaces)
+	return func(config *rest.Config, opts cache.Options) (cache.Cache, error) {
+		opts.SelectorsByObject = options.SelectorsByObject
+		return multiNamespaced(config, opts)
+	}
+}
+
+// indexPodPinger returns the UID of the CoinbasePinger the pod pings for,
+// see podPingerKey.
+func indexPodPinger(obj client.Object) []string {
+	uid, found := obj.GetLabels()[devorgv1.CRD_UID]
+	if !found {
+		return nil
+	}
+	return []string{uid}
+}
+
+// podResultPredicate passes pod updates which may change the ping result
+// collected from the pod, see podToCondition.
+var podResultPredicate = predicate.Funcs{
+	UpdateFunc: func(e event.UpdateEvent) bool {
+		oldPod, oldOk := e.ObjectOld.(*corev1.Pod)
+		newPod, newOk := e.ObjectNew.(*corev1.Pod)
+		if !oldOk || !newOk {
+			return true
+		}
+		return podResultChanged(oldPod, newPod)
+	},
+}
+
+func podResultChanged(oldPod, newPod *corev1.Pod) bool {
+	for _, label := range []string{devorgv1.TypeLabel, devorgv1.StatusLabel, devorgv1.ReasonLabel} {
+		if oldPod.Labels[label] != newPod.Labels[label] {
+			return true
+		}
+	}
+	for _, annotation := range []string{
+		devorgv1.MessageAnnotation, devorgv1.PingTimeAnnotation, devorgv1.FailedAssertionsAnnotation, devorgv1.FailedHopAnnotation,
+	} {
+		if oldPod.Annotations[annotation] != newPod.Annotations[annotation] {
+			return true
+		}
+	}
+	if oldPod.Status.Phase != newPod.Status.Phase || oldPod.Status.Reason != newPod.Status.Reason {
+		return true
+	}
+	return containerStates(oldPod) != containerStates(newPod)
+}
+
+// containerStates summarizes the container states podFailureCondition and
+// terminationMessageCondition look at.
+func containerStates(pod *corev1.Pod) string {
+	summary := ""
+	for _, status := range pod.Status.ContainerStatuses {
+		summary += status.Name + ":"
+		switch {
+		case status.State.Waiting != nil:
+			summary += "waiting/" + status.State.Waiting.Reason
+		case status.State.Terminated != nil:
+			summary += "terminated/" + status.State.Terminated.Reason
+		case status.State.Running != nil:
+			summary += "running"
+		}
+		summary += ";"
+	}
+	return summary
+}
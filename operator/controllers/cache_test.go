@@ -0,0 +1,74 @@
+package controllers
+
+import (
+	"testing"
+
+	devorgv1 "github.com/kalynv/coinbase-pinger/operator/api/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func Test_podResultChanged(t *testing.T) {
+	running := func() *corev1.Pod {
+		pod := &corev1.Pod{}
+		pod.Labels = map[string]string{devorgv1.CRD_UID: "uid"}
+		pod.Status.Phase = corev1.PodRunning
+		pod.Status.ContainerStatuses = []corev1.ContainerStatus{{
+			Name:  "pinger",
+			State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}},
+		}}
+		return pod
+	}
+
+	tests := []struct {
+		name   string
+		change func(pod *corev1.Pod)
+		want   bool
+	}{
+		{
+			name: "unrelated label",
+			change: func(pod *corev1.Pod) {
+				pod.Labels["team"] = "payments"
+			},
+		},
+		{
+			name: "condition readiness only",
+			change: func(pod *corev1.Pod) {
+				pod.Status.Conditions = []corev1.PodCondition{{Type: corev1.PodReady}}
+			},
+		},
+		{
+			name: "result labels",
+			change: func(pod *corev1.Pod) {
+				pod.Labels[devorgv1.TypeLabel] = "ServiceOnline"
+			},
+			want: true,
+		},
+		{
+			name: "phase",
+			change: func(pod *corev1.Pod) {
+				pod.Status.Phase = corev1.PodSucceeded
+			},
+			want: true,
+		},
+		{
+			name: "container waiting",
+			change: func(pod *corev1.Pod) {
+				pod.Status.ContainerStatuses[0].State = corev1.ContainerState{
+					Waiting: &corev1.ContainerStateWaiting{Reason: "CrashLoopBackOff"},
+				}
+			},
+			want: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			oldPod := running()
+			newPod := running()
+			tt.change(newPod)
+			if got := podResultChanged(oldPod, newPod); got != tt.want {
+				t.Errorf("Got changed %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -18,12 +18,15 @@ package controllers
 
 import (
 	"context"
+	"net"
 	"time"
 
-	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/runtime"
-	"k8s.io/apimachinery/pkg/selection"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/utils/pointer"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -31,32 +34,58 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
-	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	"sigs.k8s.io/controller-runtime/pkg/source"
 
+	configv1alpha1 "github.com/kalynv/coinbase-pinger/operator/api/config/v1alpha1"
 	devorgv1 "github.com/kalynv/coinbase-pinger/operator/api/v1"
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 // CoinbasePingerReconciler reconciles a CoinbasePinger object
 type CoinbasePingerReconciler struct {
 	client.Client
-	Scheme *runtime.Scheme
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
+	// Config holds the operator config, the defaults are used when nil.
+	Config *ConfigStore
+	// APIReader reads objects outside of the cache, the Client when nil.
+	APIReader client.Reader
+
+	// lookupIP resolves pinger targets, see targetPeers.
+	lookupIP func(ctx context.Context, host string) ([]net.IP, error)
+	// permissions remembers the checked ServiceAccounts, see checkPermissions.
+	permissions permissionCache
 }
 
+// PingerFinalizer keeps the CoinbasePinger until its CronJob, Jobs and pods
+// are removed.
+const PingerFinalizer = "codepinger.dev.org/finalizer"
+
 //+kubebuilder:rbac:groups=batch.dev.org,resources=coinbasepingers,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=batch.dev.org,resources=coinbasepingers/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=batch.dev.org,resources=coinbasepingers/finalizers,verbs=update
+//+kubebuilder:rbac:groups=batch,resources=cronjobs,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;create;delete;deletecollection
+//+kubebuilder:rbac:groups="",resources=pods,verbs=get;list;watch;update;delete;deletecollection
+//+kubebuilder:rbac:groups="",resources=serviceaccounts,verbs=get;list;watch;create;update;patch
+//+kubebuilder:rbac:groups=rbac.authorization.k8s.io,resources=roles;rolebindings,verbs=get;list;watch;create;update;patch
+//+kubebuilder:rbac:groups=authorization.k8s.io,resources=localsubjectaccessreviews,verbs=create
+//+kubebuilder:rbac:groups=networking.k8s.io,resources=networkpolicies,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups="",resources=endpoints,verbs=get
+//+kubebuilder:rbac:groups="",resources=events,verbs=create;patch
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
 // Create, Update and Delete corresponding child CronJob resource to reflect
 // the CoinbasePinger spec. Updates CoinbasePinger resource with ping results.
 func (r *CoinbasePingerReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
-	finalizer := "codepinger.dev.org/finalizer"
 	l := log.FromContext(ctx)
 
 	coinbasePinger := devorgv1.CoinbasePinger{}
@@ -65,19 +94,52 @@ func (r *CoinbasePingerReconciler) Reconcile(ctx context.Context, req ctrl.Reque
 		l.Error(getCoinbasePingerErr, "unable to fetch CoinbasePinger")
 		return reconcile.Result{}, client.IgnoreNotFound(getCoinbasePingerErr)
 	}
-	resourceUnderDeletion := !coinbasePinger.ObjectMeta.DeletionTimestamp.IsZero()
+	if !coinbasePinger.ObjectMeta.DeletionTimestamp.IsZero() {
+		return r.finalize(ctx, &coinbasePinger)
+	}
+	if !controllerutil.ContainsFinalizer(&coinbasePinger, PingerFinalizer) {
+		controllerutil.AddFinalizer(&coinbasePinger, PingerFinalizer)
+		if err := r.Update(ctx, &coinbasePinger); err != nil {
+			return r.retryLater(), err
+		}
+	}
+	config := r.Config.Get()
+	if err := validateInterval(coinbasePinger.Spec.Interval, config.MinInterval.Duration); err != nil {
+		return r.rejectPinger(ctx, &coinbasePinger, "InvalidInterval", err)
+	}
+	defaults := pingerDefaults(config, coinbasePinger.Namespace)
+	target, err := pingerTarget(coinbasePinger, defaults)
+	if err == nil && target != "" {
+		err = baseURLAllowed(config, target)
+	}
+	if err != nil {
+		reason := "BaseURLNotAllowed"
+		if probeType(coinbasePinger) != devorgv1.HTTPProbe {
+			reason = "TargetNotAllowed"
+		}
+		return r.rejectPinger(ctx, &coinbasePinger, reason, err)
+	}
+	if err := proxyAllowed(config, coinbasePinger.Spec.Proxy); err != nil {
+		return r.rejectPinger(ctx, &coinbasePinger, "ProxyNotAllowed", err)
+	}
+	if err := r.checkPermissions(ctx, &coinbasePinger, defaults); err != nil {
+		return r.retryLater(), err
+	}
+	if err := r.reconcileNetworkPolicy(ctx, &coinbasePinger, config, defaults); err != nil {
+		return r.retryLater(), err
+	}
 
 	cronJob, getCronJobErr := r.getCronJob(ctx, &coinbasePinger)
-	cronJobNotFound := apierrors.IsNotFound(getCronJobErr)
-
-	if cronJobNotFound && resourceUnderDeletion {
-		controllerutil.RemoveFinalizer(&coinbasePinger, finalizer)
-		err := r.Update(ctx, &coinbasePinger)
-		return reconcile.Result{}, err
-	}
-	if cronJobNotFound && !resourceUnderDeletion {
+	if apierrors.IsNotFound(getCronJobErr) {
 		l.Info("CronJob for CoinbasePinger not found. Creating")
-		cronJob := constructCronJob(coinbasePinger)
+		name, nameErr := r.newCronJobName(ctx, &coinbasePinger)
+		if nameErr != nil {
+			return r.retryLater(), nameErr
+		}
+		cronJob, constructErr := r.desiredCronJob(&coinbasePinger, name, defaults)
+		if constructErr != nil {
+			return ctrl.Result{}, constructErr
+		}
 		createErr := r.Create(ctx, cronJob)
 		requeue := false
 		if createErr != nil {
@@ -87,36 +149,176 @@ func (r *CoinbasePingerReconciler) Reconcile(ctx context.Context, req ctrl.Reque
 		if apierrors.IsAlreadyExists(createErr) {
 			requeue = false
 		}
-		return ctrl.Result{Requeue: requeue, RequeueAfter: time.Second * 10}, createErr
+		return ctrl.Result{Requeue: requeue, RequeueAfter: config.RequeueAfter.Duration}, createErr
 	}
 	if getCronJobErr != nil {
-		return ctrl.Result{Requeue: true, RequeueAfter: time.Second * 10}, getCronJobErr
+		return r.retryLater(), getCronJobErr
 	}
-	if resourceUnderDeletion {
-		if err := r.Delete(ctx, cronJob); err != nil {
-			l.Error(err, "Could not delete CronJob")
-			return ctrl.Result{Requeue: true, RequeueAfter: time.Second * 10}, err
+
+	updatedCronJob, constructErr := r.desiredCronJob(&coinbasePinger, cronJob.Name, defaults)
+	if constructErr != nil {
+		return ctrl.Result{}, constructErr
+	}
+	if cronjobChanged(cronJob, updatedCronJob) {
+		if result, err := r.updateCronJob(ctx, cronJob, updatedCronJob); err != nil {
+			return result, err
 		}
-		controllerutil.RemoveFinalizer(&coinbasePinger, finalizer)
-		err := r.Update(ctx, &coinbasePinger)
-		return ctrl.Result{}, err
 	}
 
-	updatedCronJob := constructCronJob(coinbasePinger)
-	if cronjobChanged(cronJob, updatedCronJob) {
-		r.recreateCronJob(ctx, cronJob, updatedCronJob)
+	if nonce, pending := pendingPingNow(coinbasePinger); pending {
+		if err := r.pingNow(ctx, &coinbasePinger, cronJob, nonce); err != nil {
+			return r.retryLater(), err
+		}
 	}
 
-	updateCoinbasePingerErr := r.updateCoinbasePingerStatus(ctx, coinbasePinger)
+	recheckAfter, updateCoinbasePingerErr := r.updateCoinbasePingerStatus(ctx, coinbasePinger)
 	if updateCoinbasePingerErr != nil {
-		return ctrl.Result{Requeue: true, RequeueAfter: time.Second * 10}, updateCoinbasePingerErr
+		return r.retryLater(), updateCoinbasePingerErr
+	}
+	return ctrl.Result{RequeueAfter: recheckAfter}, nil
+}
+
+// rejectPinger suspends the CronJob of a pinger its spec or the operator
+// config does not allow, so it stops pinging, and records why. Retrying does
+// not help, the pinger is reconciled again on spec and operator config
+// change, and the CronJob is resumed once the pinger is accepted again.
+func (r *CoinbasePingerReconciler) rejectPinger(
+	ctx context.Context,
+	pinger *devorgv1.CoinbasePinger,
+	reason string,
+	rejectErr error,
+) (ctrl.Result, error) {
+	l := log.FromContext(ctx)
+	l.Error(rejectErr, "CoinbasePinger spec is invalid")
+
+	cronJob, getCronJobErr := r.getCronJob(ctx, pinger)
+	if getCronJobErr != nil && !apierrors.IsNotFound(getCronJobErr) {
+		return r.retryLater(), getCronJobErr
+	}
+	if getCronJobErr == nil && (cronJob.Spec.Suspend == nil || !*cronJob.Spec.Suspend) {
+		l.Info("suspending CronJob of rejected CoinbasePinger", "CronJob", cronJob.Name)
+		patch := client.MergeFrom(cronJob.DeepCopy())
+		cronJob.Spec.Suspend = pointer.BoolPtr(true)
+		if err := r.Patch(ctx, cronJob, patch); err != nil {
+			return r.retryLater(), err
+		}
+	}
+
+	rejected := devorgv1.Condition{
+		Type:     devorgv1.PingerRejected,
+		Status:   false,
+		Reason:   reason,
+		Message:  rejectErr.Error(),
+		PingTime: metav1.Now(),
+	}
+	recorded := false
+	err := r.patchStatus(ctx, pinger, func(status *devorgv1.CoinbasePingerStatus) {
+		if n := len(status.Conditions); n > 0 && sameRejection(status.Conditions[n-1], rejected) {
+			return
+		}
+		status.Conditions = mergeConditions(status.Conditions, []devorgv1.Condition{rejected}, maxStatusHistory)
+		recorded = true
+	})
+	if err != nil {
+		return r.retryLater(), err
+	}
+	// the Event is sent once per rejection, not on every reconcile
+	if recorded {
+		r.Recorder.Event(pinger, corev1.EventTypeWarning, reason, rejectErr.Error())
+		recordMetrics(*pinger, pinger.Status.Conditions)
 	}
 	return ctrl.Result{}, nil
 }
 
+// sameRejection tells whether the newest recorded condition already is the
+// rejection, so the status is not written on every reconcile.
+func sameRejection(recorded, rejected devorgv1.Condition) bool {
+	return recorded.Type == rejected.Type &&
+		recorded.Reason == rejected.Reason &&
+		recorded.Message == rejected.Message
+}
+
+// finalize removes the CronJob, in-flight Jobs and pods of a deleted pinger,
+// waits until they are gone, sends the final notification and only then
+// releases the finalizer.
+func (r *CoinbasePingerReconciler) finalize(
+	ctx context.Context,
+	pinger *devorgv1.CoinbasePinger,
+) (ctrl.Result, error) {
+	l := log.FromContext(ctx)
+	if !controllerutil.ContainsFinalizer(pinger, PingerFinalizer) {
+		return ctrl.Result{}, nil
+	}
+	background := client.PropagationPolicy(metav1.DeletePropagationBackground)
+
+	cronJob, getCronJobErr := r.getCronJob(ctx, pinger)
+	if getCronJobErr == nil {
+		if err := r.Delete(ctx, cronJob, background); err != nil && !apierrors.IsNotFound(err) {
+			l.Error(err, "Could not delete CronJob")
+			return r.retryLater(), err
+		}
+	} else if !apierrors.IsNotFound(getCronJobErr) {
+		return r.retryLater(), getCronJobErr
+	}
+
+	own := []client.DeleteAllOfOption{
+		client.InNamespace(pinger.Namespace),
+		client.MatchingLabels{devorgv1.CRD_UID: string(pinger.UID)},
+		background,
+	}
+	if err := r.DeleteAllOf(ctx, &batchv1.Job{}, own...); err != nil {
+		l.Error(err, "Could not delete Jobs")
+		return r.retryLater(), err
+	}
+	if err := r.DeleteAllOf(ctx, &corev1.Pod{}, own...); err != nil {
+		l.Error(err, "Could not delete pods")
+		return r.retryLater(), err
+	}
+	pods, getPodsErr := r.getOwnPods(ctx, *pinger)
+	if getPodsErr != nil {
+		return r.retryLater(), getPodsErr
+	}
+	if len(pods) > 0 {
+		l.Info("waiting for pinger pods to terminate", "pods", len(pods))
+		return ctrl.Result{RequeueAfter: time.Second * 5}, nil
+	}
+
+	r.Recorder.Event(pinger, corev1.EventTypeNormal, "PingerRemoved", "CronJob, Jobs and pods of the pinger are removed")
+	deleteMetrics(*pinger)
+
+	controllerutil.RemoveFinalizer(pinger, PingerFinalizer)
+	err := r.Update(ctx, pinger)
+	return ctrl.Result{}, client.IgnoreNotFound(err)
+}
+
+// getCronJob finds the CronJob controlled by the pinger. CronJobs created
+// before controller references were set are named with the pinger UID,
+// these are adopted and keep their name.
 func (r *CoinbasePingerReconciler) getCronJob(
 	ctx context.Context,
 	pinger *devorgv1.CoinbasePinger,
+) (*batchv1.CronJob, error) {
+	list := batchv1.CronJobList{}
+	err := r.List(
+		ctx,
+		&list,
+		client.InNamespace(pinger.Namespace),
+		client.MatchingFields{cronJobOwnerKey: pinger.Name},
+	)
+	if err != nil {
+		return nil, err
+	}
+	for i := range list.Items {
+		if metav1.IsControlledBy(&list.Items[i], pinger) {
+			return &list.Items[i], nil
+		}
+	}
+	return r.adoptLegacyCronJob(ctx, pinger)
+}
+
+func (r *CoinbasePingerReconciler) adoptLegacyCronJob(
+	ctx context.Context,
+	pinger *devorgv1.CoinbasePinger,
 ) (*batchv1.CronJob, error) {
 	cronJob := &batchv1.CronJob{}
 	err := r.Get(
@@ -127,80 +329,225 @@ func (r *CoinbasePingerReconciler) getCronJob(
 		},
 		cronJob,
 	)
+	if err != nil {
+		return nil, err
+	}
+	if metav1.GetControllerOf(cronJob) != nil {
+		return nil, apierrors.NewNotFound(batchv1.Resource("cronjobs"), cronJob.Name)
+	}
+	log.FromContext(ctx).Info("adopting CronJob", "CronJob", cronJob.Name)
+	if err := controllerutil.SetControllerReference(pinger, cronJob, r.Scheme); err != nil {
+		return nil, err
+	}
+	if err := r.Update(ctx, cronJob); err != nil {
+		return nil, err
+	}
+	return cronJob, nil
+}
+
+// newCronJobName picks the readable name for a new CronJob, falling back
+// to a UID salted one if the name is taken by another CronJob.
+func (r *CoinbasePingerReconciler) newCronJobName(
+	ctx context.Context,
+	pinger *devorgv1.CoinbasePinger,
+) (string, error) {
+	name := cronJobName(pinger.Name, "")
+	err := r.Get(
+		ctx,
+		types.NamespacedName{Name: name, Namespace: pinger.Namespace},
+		&batchv1.CronJob{},
+	)
+	if apierrors.IsNotFound(err) {
+		return name, nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return cronJobName(pinger.Name, string(pinger.UID)), nil
+}
+
+func (r *CoinbasePingerReconciler) desiredCronJob(
+	pinger *devorgv1.CoinbasePinger,
+	name string,
+	defaults configv1alpha1.PingerDefaults,
+) (*batchv1.CronJob, error) {
+	cronJob, err := constructCronJob(*pinger, name, defaults)
+	if err != nil {
+		return nil, err
+	}
+	err = controllerutil.SetControllerReference(pinger, cronJob, r.Scheme)
 	return cronJob, err
 }
 
-func (r *CoinbasePingerReconciler) recreateCronJob(
+// updateCronJob updates the CronJob in place to keep its job history and
+// avoid double runs. The CronJob is recreated only when the API server
+// rejects the update as invalid, e.g. for an immutable field.
+func (r *CoinbasePingerReconciler) updateCronJob(
 	ctx context.Context,
 	oldCronJob *batchv1.CronJob,
 	updatedCronJob *batchv1.CronJob,
 ) (ctrl.Result, error) {
 	l := log.FromContext(ctx)
 	l.Info(
-		"CoinbasePinger spec changed, recreating CronJob",
-		"Old Cronjob name",
+		"CoinbasePinger spec changed, updating CronJob",
+		"Cronjob name",
 		oldCronJob.Name,
-		"Old Cronjob UID",
-		oldCronJob.UID,
 		"Old Schedule",
 		oldCronJob.Spec.Schedule,
 		"New Schedule",
 		updatedCronJob.Spec.Schedule,
 	)
-	if err := r.Delete(ctx, oldCronJob); err != nil {
-		return ctrl.Result{Requeue: true, RequeueAfter: time.Second * 10}, err
+	cronJob := oldCronJob.DeepCopy()
+	if cronJob.Annotations == nil {
+		cronJob.Annotations = map[string]string{}
+	}
+	for key, value := range updatedCronJob.Annotations {
+		cronJob.Annotations[key] = value
+	}
+	cronJob.Spec = updatedCronJob.Spec
+	err := r.Update(ctx, cronJob)
+	if apierrors.IsInvalid(err) {
+		l.Info("CronJob can not be updated in place", "reason", err.Error())
+		return r.recreateCronJob(ctx, oldCronJob, updatedCronJob)
+	}
+	if err != nil {
+		l.Error(err, "unable to update CronJob")
+		return r.retryLater(), err
+	}
+	*oldCronJob = *cronJob
+	return ctrl.Result{}, nil
+}
+
+func (r *CoinbasePingerReconciler) recreateCronJob(
+	ctx context.Context,
+	oldCronJob *batchv1.CronJob,
+	updatedCronJob *batchv1.CronJob,
+) (ctrl.Result, error) {
+	l := log.FromContext(ctx)
+	l.Info(
+		"recreating CronJob",
+		"Old Cronjob name",
+		oldCronJob.Name,
+		"Old Cronjob UID",
+		oldCronJob.UID,
+	)
+	propagation := client.PropagationPolicy(metav1.DeletePropagationBackground)
+	if err := r.Delete(ctx, oldCronJob, propagation); err != nil && !apierrors.IsNotFound(err) {
+		return r.retryLater(), err
 	}
 	if err := r.Create(ctx, updatedCronJob); err != nil {
-		return ctrl.Result{Requeue: true, RequeueAfter: time.Second * 10}, err
+		return r.retryLater(), err
 	}
 	l.Info("recreated cronjob", "Schedule", updatedCronJob.Spec.Schedule)
+	*oldCronJob = *updatedCronJob
 	return ctrl.Result{}, nil
 }
 
-func (r *CoinbasePingerReconciler) updateCoinbasePingerStatus(
+// pingNow creates a one-off Job for the ping-now nonce and records the nonce
+// as handled in the CoinbasePinger status.
+func (r *CoinbasePingerReconciler) pingNow(
 	ctx context.Context,
-	pinger devorgv1.CoinbasePinger,
+	pinger *devorgv1.CoinbasePinger,
+	cronJob *batchv1.CronJob,
+	nonce string,
 ) error {
 	l := log.FromContext(ctx)
+	job := constructPingNowJob(*pinger, cronJob, nonce)
+	if err := controllerutil.SetControllerReference(pinger, job, r.Scheme); err != nil {
+		return err
+	}
+	createErr := r.Create(ctx, job)
+	if createErr == nil {
+		l.Info("created ping-now Job", "Job", job.Name, "nonce", nonce)
+	} else if !apierrors.IsAlreadyExists(createErr) {
+		l.Error(createErr, "unable to create ping-now Job", "nonce", nonce)
+		return createErr
+	}
+
+	return r.patchStatus(ctx, pinger, func(status *devorgv1.CoinbasePingerStatus) {
+		status.LastHandledPingNow = nonce
+	})
+}
+
+// updateCoinbasePingerStatus collects ping results from own pods. It returns
+// when to check the pods again, if some are pending and may turn out to be
+// stuck, see podFailureCondition.
+func (r *CoinbasePingerReconciler) updateCoinbasePingerStatus(
+	ctx context.Context,
+	pinger devorgv1.CoinbasePinger,
+) (time.Duration, error) {
 	pods, getPodsErr := r.getOwnPods(ctx, pinger)
 	if getPodsErr != nil {
-		return getPodsErr
+		return 0, getPodsErr
 	}
 
-	conditions := podsToConditions(pods, log.FromContext(ctx))
-	if len(conditions) == 0 {
-		l.Info("status is empty")
-		return nil
+	var recheckAfter time.Duration
+	timeout := pendingTimeout(pinger)
+	if hasPendingPods(pods) {
+		// a little later, so the pods are pending for longer than timeout
+		recheckAfter = timeout + time.Second
 	}
 
-	l.Info("updating status", "Conditions", conditions)
+	collected := podsToConditions(pods, timeout, log.FromContext(ctx))
+	updateErr := r.patchStatus(ctx, &pinger, func(status *devorgv1.CoinbasePingerStatus) {
+		status.Conditions = mergeConditions(status.Conditions, collected, maxStatusHistory)
+		status.ObservedGeneration = pinger.Generation
+	})
+	if updateErr == nil {
+		recordMetrics(pinger, pinger.Status.Conditions)
+	}
+	return recheckAfter, updateErr
+}
 
-	updatedCodebasePinger := pinger.DeepCopy()
-	updatedCodebasePinger.Status.Conditions = conditions
-	updateErr := r.Status().Update(ctx, updatedCodebasePinger)
+// patchStatus applies mutate to the pinger status and merge patches the
+// change, guarded by the resourceVersion. On conflict the pinger is fetched
+// again and mutate reapplied. Nothing is written when mutate changes
+// nothing, to spare the API server and watchers.
+func (r *CoinbasePingerReconciler) patchStatus(
+	ctx context.Context,
+	pinger *devorgv1.CoinbasePinger,
+	mutate func(status *devorgv1.CoinbasePingerStatus),
+) error {
+	l := log.FromContext(ctx)
+	refetch := false
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		if refetch {
+			// decoded into a new object, so fields mutate set are not kept
+			latest := &devorgv1.CoinbasePinger{}
+			if err := r.Get(ctx, client.ObjectKeyFromObject(pinger), latest); err != nil {
+				return err
+			}
+			*pinger = *latest
+		}
+		refetch = true
 
-	return updateErr
+		original := pinger.DeepCopy()
+		mutate(&pinger.Status)
+		if equality.Semantic.DeepEqual(original.Status, pinger.Status) {
+			return nil
+		}
+		l.Info("updating status", "Conditions", len(pinger.Status.Conditions))
+		patch := client.MergeFromWithOptions(original, client.MergeFromWithOptimisticLock{})
+		return r.Status().Patch(ctx, pinger, patch)
+	})
+}
+
+// retryLater is the result of reconciles which failed on an error a retry
+// may fix.
+func (r *CoinbasePingerReconciler) retryLater() ctrl.Result {
+	return ctrl.Result{Requeue: true, RequeueAfter: r.Config.Get().RequeueAfter.Duration}
 }
 
 func (r *CoinbasePingerReconciler) getOwnPods(
 	ctx context.Context,
 	pinger devorgv1.CoinbasePinger,
 ) ([]corev1.Pod, error) {
-	requirement, _ := labels.NewRequirement(
-		CRD_UID,
-		selection.Equals,
-		[]string{string(pinger.UID)},
-	)
-	selector := labels.NewSelector().Add(*requirement)
-
 	list := corev1.PodList{}
 	err := r.List(
 		ctx,
 		&list,
-		&client.ListOptions{
-			LabelSelector: selector,
-			Namespace:     pinger.Namespace,
-		},
+		client.InNamespace(pinger.Namespace),
+		client.MatchingFields{podPingerKey: string(pinger.UID)},
 	)
 
 	return list.Items, err
@@ -208,18 +555,52 @@ func (r *CoinbasePingerReconciler) getOwnPods(
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *CoinbasePingerReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	err := mgr.GetFieldIndexer().IndexField(
+		context.Background(),
+		&batchv1.CronJob{},
+		cronJobOwnerKey,
+		indexCronJobOwner,
+	)
+	if err != nil {
+		return err
+	}
+	err = mgr.GetFieldIndexer().IndexField(
+		context.Background(),
+		&corev1.Pod{},
+		podPingerKey,
+		indexPodPinger,
+	)
+	if err != nil {
+		return err
+	}
+	configChanged := make(chan event.GenericEvent)
+	if r.Config != nil {
+		err = mgr.Add(manager.RunnableFunc(func(ctx context.Context) error {
+			return r.Config.Watch(ctx, func() { r.enqueueAll(ctx, configChanged) })
+		}))
+		if err != nil {
+			return err
+		}
+	}
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&devorgv1.CoinbasePinger{}).
+		Watches(&source.Channel{Source: configChanged}, &handler.EnqueueRequestForObject{}).
+		Owns(&batchv1.CronJob{}).
+		Owns(&batchv1.Job{}).
+		Owns(&corev1.ServiceAccount{}).
+		Owns(&rbacv1.Role{}).
+		Owns(&rbacv1.RoleBinding{}).
+		Owns(&networkingv1.NetworkPolicy{}).
 		Watches(
 			&source.Kind{Type: &corev1.Pod{}},
 			handler.EnqueueRequestsFromMapFunc(
 				func(obj client.Object) []reconcile.Request {
 					labels := obj.GetLabels()
-					name, namePresent := labels[CRD_NAME]
+					name, namePresent := labels[devorgv1.CRD_NAME]
 					if !namePresent {
 						return nil
 					}
-					namespace, namespacePresent := labels[CRD_NAMESPACE]
+					namespace, namespacePresent := labels[devorgv1.CRD_NAMESPACE]
 					if !namespacePresent {
 						return nil
 					}
@@ -233,13 +614,24 @@ func (r *CoinbasePingerReconciler) SetupWithManager(mgr ctrl.Manager) error {
 					}
 				},
 			),
-			builder.WithPredicates(
-				&predicate.Funcs{
-					UpdateFunc: func(event.UpdateEvent) bool {
-						return true
-					},
-				},
-			),
+			builder.WithPredicates(podResultPredicate),
 		).
 		Complete(r)
 }
+
+// enqueueAll reconciles all pingers again, after the operator config
+// changed.
+func (r *CoinbasePingerReconciler) enqueueAll(ctx context.Context, events chan<- event.GenericEvent) {
+	list := devorgv1.CoinbasePingerList{}
+	if err := r.List(ctx, &list); err != nil {
+		log.FromContext(ctx).Error(err, "unable to list CoinbasePingers after config change")
+		return
+	}
+	for i := range list.Items {
+		select {
+		case events <- event.GenericEvent{Object: &list.Items[i]}:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,307 @@
+package controllers
+
+import (
+	"context"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	devorgv1 "github.com/kalynv/coinbase-pinger/operator/api/v1"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+const (
+	timeout  = time.Second * 10
+	interval = time.Millisecond * 250
+)
+
+func newPinger(name string) *devorgv1.CoinbasePinger {
+	return &devorgv1.CoinbasePinger{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "default",
+		},
+		Spec: devorgv1.CoinbasePingerSpec{Interval: "5m"},
+	}
+}
+
+// newPingerPod returns a pod as the CronJob of the pinger would run it.
+func newPingerPod(pinger *devorgv1.CoinbasePinger, name string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: pinger.Namespace,
+			Labels: map[string]string{
+				devorgv1.CRD_UID:       string(pinger.UID),
+				devorgv1.CRD_NAME:      pinger.Name,
+				devorgv1.CRD_NAMESPACE: pinger.Namespace,
+			},
+		},
+		Spec: corev1.PodSpec{
+			RestartPolicy: corev1.RestartPolicyNever,
+			Containers:    []corev1.Container{{Name: "pinger", Image: "kalynv/webapp-pinger"}},
+		},
+	}
+}
+
+// hasEvent reports whether an event with the reason was recorded for the
+// object.
+func hasEvent(ctx context.Context, obj client.Object, reason string) bool {
+	events := corev1.EventList{}
+	if err := k8sClient.List(ctx, &events, client.InNamespace(obj.GetNamespace())); err != nil {
+		return false
+	}
+	for _, event := range events.Items {
+		if event.InvolvedObject.UID == obj.GetUID() && event.Reason == reason {
+			return true
+		}
+	}
+	return false
+}
+
+var _ = Describe("CoinbasePinger controller", func() {
+	ctx := context.Background()
+
+	It("creates a CronJob and updates it in place on spec change", func() {
+		pinger := newPinger("lifecycle")
+		Expect(k8sClient.Create(ctx, pinger)).To(Succeed())
+
+		cronJob := &batchv1.CronJob{}
+		cronJobKey := types.NamespacedName{Name: "lifecycle-pinger", Namespace: pinger.Namespace}
+		Eventually(func() error {
+			return k8sClient.Get(ctx, cronJobKey, cronJob)
+		}, timeout, interval).Should(Succeed())
+		Expect(cronJob.Spec.Schedule).To(Equal("*/5 * * * *"))
+		Expect(metav1.IsControlledBy(cronJob, pinger)).To(BeTrue())
+		Expect(cronJob.Spec.JobTemplate.Spec.Template.Labels).To(HaveKeyWithValue(devorgv1.CRD_UID, string(pinger.UID)))
+		uid := cronJob.UID
+
+		By("changing the interval")
+		Eventually(func() error {
+			if err := k8sClient.Get(ctx, client.ObjectKeyFromObject(pinger), pinger); err != nil {
+				return err
+			}
+			pinger.Spec.Interval = "10m"
+			return k8sClient.Update(ctx, pinger)
+		}, timeout, interval).Should(Succeed())
+
+		Eventually(func() string {
+			if err := k8sClient.Get(ctx, cronJobKey, cronJob); err != nil {
+				return ""
+			}
+			return cronJob.Spec.Schedule
+		}, timeout, interval).Should(Equal("*/10 * * * *"))
+		Expect(cronJob.UID).To(Equal(uid))
+
+		Expect(k8sClient.Delete(ctx, pinger)).To(Succeed())
+	})
+
+	It("provisions a ServiceAccount with the permissions the pinger pods need", func() {
+		pinger := newPinger("rbac")
+		Expect(k8sClient.Create(ctx, pinger)).To(Succeed())
+
+		key := types.NamespacedName{Name: "rbac-pinger", Namespace: pinger.Namespace}
+		for _, obj := range []client.Object{&corev1.ServiceAccount{}, &rbacv1.Role{}, &rbacv1.RoleBinding{}} {
+			Eventually(func() error {
+				return k8sClient.Get(ctx, key, obj)
+			}, timeout, interval).Should(Succeed())
+			Expect(metav1.IsControlledBy(obj, pinger)).To(BeTrue())
+		}
+
+		cronJob := &batchv1.CronJob{}
+		Eventually(func() error {
+			return k8sClient.Get(ctx, key, cronJob)
+		}, timeout, interval).Should(Succeed())
+		Expect(cronJob.Spec.JobTemplate.Spec.Template.Spec.ServiceAccountName).To(Equal("rbac-pinger"))
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(pinger), pinger)).To(Succeed())
+		Expect(pinger.Status.MissingPermissions).To(BeEmpty())
+
+		Expect(k8sClient.Delete(ctx, pinger)).To(Succeed())
+	})
+
+	It("reports a foreign ServiceAccount and still creates the CronJob", func() {
+		foreign := &corev1.ServiceAccount{
+			ObjectMeta: metav1.ObjectMeta{Name: "foreign-pinger", Namespace: "default"},
+		}
+		Expect(k8sClient.Create(ctx, foreign)).To(Succeed())
+		pinger := newPinger("foreign")
+		Expect(k8sClient.Create(ctx, pinger)).To(Succeed())
+
+		Eventually(func() bool {
+			return hasEvent(ctx, pinger, "RBACNotProvisioned")
+		}, timeout, interval).Should(BeTrue())
+		cronJob := &batchv1.CronJob{}
+		Eventually(func() error {
+			return k8sClient.Get(ctx, types.NamespacedName{Name: "foreign-pinger", Namespace: "default"}, cronJob)
+		}, timeout, interval).Should(Succeed())
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(foreign), foreign)).To(Succeed())
+		Expect(foreign.OwnerReferences).To(BeEmpty())
+
+		Expect(k8sClient.Delete(ctx, pinger)).To(Succeed())
+		Expect(k8sClient.Delete(ctx, foreign)).To(Succeed())
+	})
+
+	It("collects ping results from labelled pods into the status", func() {
+		pinger := newPinger("status")
+		Expect(k8sClient.Create(ctx, pinger)).To(Succeed())
+		Eventually(func() bool {
+			if err := k8sClient.Get(ctx, client.ObjectKeyFromObject(pinger), pinger); err != nil {
+				return false
+			}
+			return controllerutil.ContainsFinalizer(pinger, PingerFinalizer)
+		}, timeout, interval).Should(BeTrue())
+
+		pod := newPingerPod(pinger, "status-result")
+		pod.Labels[devorgv1.TypeLabel] = "ServiceOnline"
+		pod.Labels[devorgv1.StatusLabel] = "true"
+		pod.Labels[devorgv1.ReasonLabel] = "PingSucceeded"
+		pod.Annotations = map[string]string{
+			devorgv1.MessageAnnotation:  `{"data":{"amount":"1"}}`,
+			devorgv1.PingTimeAnnotation: `"2021-09-01T12:00:00Z"`,
+		}
+		Expect(k8sClient.Create(ctx, pod)).To(Succeed())
+
+		Eventually(func() []devorgv1.Condition {
+			if err := k8sClient.Get(ctx, client.ObjectKeyFromObject(pinger), pinger); err != nil {
+				return nil
+			}
+			return pinger.Status.Conditions
+		}, timeout, interval).Should(HaveLen(1))
+		condition := pinger.Status.Conditions[0]
+		Expect(condition.Type).To(Equal("ServiceOnline"))
+		Expect(condition.Status).To(BeTrue())
+		Expect(condition.Reason).To(Equal("PingSucceeded"))
+		Expect(condition.Message).To(Equal(`{"data":{"amount":"1"}}`))
+
+		Expect(k8sClient.Delete(ctx, pinger)).To(Succeed())
+	})
+
+	It("rejects an invalid interval without creating a CronJob", func() {
+		pinger := newPinger("invalid")
+		pinger.Spec.Interval = "30s"
+		Expect(k8sClient.Create(ctx, pinger)).To(Succeed())
+
+		Eventually(func() bool {
+			return hasEvent(ctx, pinger, "InvalidInterval")
+		}, timeout, interval).Should(BeTrue())
+		Consistently(func() bool {
+			cronJobs := batchv1.CronJobList{}
+			if err := k8sClient.List(ctx, &cronJobs, client.InNamespace(pinger.Namespace)); err != nil {
+				return false
+			}
+			for _, cronJob := range cronJobs.Items {
+				if metav1.IsControlledBy(&cronJob, pinger) {
+					return false
+				}
+			}
+			return true
+		}, time.Second*2, interval).Should(BeTrue())
+
+		Expect(k8sClient.Delete(ctx, pinger)).To(Succeed())
+	})
+
+	It("suspends the CronJob while the operator config rejects the base URL", func() {
+		pinger := newPinger("rejected")
+		Expect(k8sClient.Create(ctx, pinger)).To(Succeed())
+
+		cronJob := &batchv1.CronJob{}
+		cronJobKey := types.NamespacedName{Name: "rejected-pinger", Namespace: pinger.Namespace}
+		Eventually(func() error {
+			return k8sClient.Get(ctx, cronJobKey, cronJob)
+		}, timeout, interval).Should(Succeed())
+		Expect(*cronJob.Spec.Suspend).To(BeFalse())
+		suspended := func() bool {
+			if err := k8sClient.Get(ctx, cronJobKey, cronJob); err != nil {
+				return false
+			}
+			return cronJob.Spec.Suspend != nil && *cronJob.Spec.Suspend
+		}
+
+		By("narrowing the allowed base URL domains")
+		narrower := strings.Replace(suiteOperatorConfig, "- coinbase.com", "- example.org", 1)
+		Expect(ioutil.WriteFile(operatorConfigPath, []byte(narrower), 0o600)).To(Succeed())
+		Eventually(suspended, timeout, interval).Should(BeTrue())
+		Eventually(func() string {
+			if err := k8sClient.Get(ctx, client.ObjectKeyFromObject(pinger), pinger); err != nil {
+				return ""
+			}
+			conditions := pinger.Status.Conditions
+			if len(conditions) == 0 || conditions[len(conditions)-1].Type != devorgv1.PingerRejected {
+				return ""
+			}
+			return conditions[len(conditions)-1].Reason
+		}, timeout, interval).Should(Equal("BaseURLNotAllowed"))
+		Expect(hasEvent(ctx, pinger, "BaseURLNotAllowed")).To(BeTrue())
+
+		By("allowing the base URL again")
+		Expect(ioutil.WriteFile(operatorConfigPath, []byte(suiteOperatorConfig), 0o600)).To(Succeed())
+		Eventually(suspended, timeout, interval).Should(BeFalse())
+
+		Expect(k8sClient.Delete(ctx, pinger)).To(Succeed())
+	})
+})
+
+var _ = Describe("CoinbasePinger finalizer", func() {
+	ctx := context.Background()
+
+	It("removes the CronJob, Jobs and pods before releasing the pinger", func() {
+		pinger := newPinger("finalizer")
+		Expect(k8sClient.Create(ctx, pinger)).To(Succeed())
+		key := types.NamespacedName{Name: pinger.Name, Namespace: pinger.Namespace}
+
+		By("adding the finalizer on the first reconcile")
+		Eventually(func() bool {
+			if err := k8sClient.Get(ctx, key, pinger); err != nil {
+				return false
+			}
+			return controllerutil.ContainsFinalizer(pinger, PingerFinalizer)
+		}, timeout, interval).Should(BeTrue())
+
+		cronJob := &batchv1.CronJob{}
+		cronJobKey := types.NamespacedName{Name: cronJobName(pinger.Name, ""), Namespace: pinger.Namespace}
+		Eventually(func() error {
+			return k8sClient.Get(ctx, cronJobKey, cronJob)
+		}, timeout, interval).Should(Succeed())
+		Expect(metav1.IsControlledBy(cronJob, pinger)).To(BeTrue())
+
+		job := &batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "finalizer-in-flight",
+				Namespace: pinger.Namespace,
+				Labels:    map[string]string{devorgv1.CRD_UID: string(pinger.UID)},
+			},
+			Spec: cronJob.Spec.JobTemplate.Spec,
+		}
+		Expect(k8sClient.Create(ctx, job)).To(Succeed())
+		pod := newPingerPod(pinger, "finalizer-in-flight")
+		Expect(k8sClient.Create(ctx, pod)).To(Succeed())
+
+		By("deleting the pinger")
+		Expect(k8sClient.Delete(ctx, pinger)).To(Succeed())
+
+		Eventually(func() bool {
+			return apierrors.IsNotFound(k8sClient.Get(ctx, key, &devorgv1.CoinbasePinger{}))
+		}, timeout, interval).Should(BeTrue())
+		Expect(apierrors.IsNotFound(k8sClient.Get(ctx, cronJobKey, &batchv1.CronJob{}))).To(BeTrue())
+		Expect(apierrors.IsNotFound(
+			k8sClient.Get(ctx, client.ObjectKeyFromObject(job), &batchv1.Job{}),
+		)).To(BeTrue())
+		Expect(apierrors.IsNotFound(
+			k8sClient.Get(ctx, client.ObjectKeyFromObject(pod), &corev1.Pod{}),
+		)).To(BeTrue())
+
+		By("sending the pinger removed event")
+		Eventually(func() bool {
+			return hasEvent(ctx, pinger, "PingerRemoved")
+		}, timeout, interval).Should(BeTrue())
+	})
+})
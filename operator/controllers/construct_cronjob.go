@@ -4,70 +4,140 @@ import (
 	"fmt"
 	"time"
 
+	configv1alpha1 "github.com/kalynv/coinbase-pinger/operator/api/config/v1alpha1"
 	devorgv1 "github.com/kalynv/coinbase-pinger/operator/api/v1"
 	batchv1 "k8s.io/api/batch/v1"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
 )
 
-const (
-	CRD_UID       string = "webapp-pinger"
-	CRD_NAME      string = "notify-name"
-	CRD_NAMESPACE string = "notify-namespace"
-)
+// defaultActiveDeadlineSeconds matches the CRD default, see
+// pendingTimeout for how it bounds pending pods.
+const defaultActiveDeadlineSeconds int64 = 120
 
-func constructCronJob(pinger devorgv1.CoinbasePinger) *batchv1.CronJob {
+// constructCronJob builds the desired CronJob named name, with pods
+// configured by defaults where the pinger spec leaves them open. The
+// controller reference is set by the reconciler. It fails for intervals
+// which have no schedule, see intervalToCrontabSchedule.
+func constructCronJob(
+	pinger devorgv1.CoinbasePinger,
+	name string,
+	defaults configv1alpha1.PingerDefaults,
+) (*batchv1.CronJob, error) {
+	schedule, err := intervalToCrontabSchedule(pinger.Spec.Interval)
+	if err != nil {
+		return nil, fmt.Errorf("invalid interval %q: %w", pinger.Spec.Interval, err)
+	}
 	cronjob := &batchv1.CronJob{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      string(pinger.UID),
+			Name:      name,
 			Namespace: pinger.Namespace,
-			OwnerReferences: []metav1.OwnerReference{
-				{
-					APIVersion: pinger.APIVersion,
-					Kind:       pinger.Kind,
-					Name:       pinger.Name,
-					UID:        pinger.UID,
-				},
-			},
 		},
 		Spec: batchv1.CronJobSpec{
-			Schedule:          intervalToCrontabSchedule(pinger.Spec.Interval),
-			ConcurrencyPolicy: batchv1.ForbidConcurrent,
+			Schedule:          schedule,
+			ConcurrencyPolicy: concurrencyPolicy(pinger.Spec.ConcurrencyPolicy),
+			// set explicitly so that changes made by hand are reverted
+			Suspend:                    pointer.BoolPtr(false),
+			SuccessfulJobsHistoryLimit: int32OrDefault(pinger.Spec.SuccessfulJobsHistoryLimit, 3),
+			FailedJobsHistoryLimit:     int32OrDefault(pinger.Spec.FailedJobsHistoryLimit, 1),
+			StartingDeadlineSeconds:    int64OrDefault(pinger.Spec.StartingDeadlineSeconds, 60),
 			JobTemplate: batchv1.JobTemplateSpec{
+				// labelled so Jobs can be removed with the pinger
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{devorgv1.CRD_UID: string(pinger.UID)},
+				},
 				Spec: batchv1.JobSpec{
+					ActiveDeadlineSeconds:   int64OrDefault(pinger.Spec.ActiveDeadlineSeconds, defaultActiveDeadlineSeconds),
+					BackoffLimit:            int32OrDefault(pinger.Spec.BackoffLimit, 0),
+					TTLSecondsAfterFinished: int32OrDefault(pinger.Spec.TTLSecondsAfterFinished, 86400),
 					Template: v1.PodTemplateSpec{
 						ObjectMeta: metav1.ObjectMeta{
 							Labels: map[string]string{
-								CRD_UID:       string(pinger.UID),
-								CRD_NAME:      pinger.Name,
-								CRD_NAMESPACE: pinger.Namespace,
+								devorgv1.CRD_UID:       string(pinger.UID),
+								devorgv1.CRD_NAME:      pinger.Name,
+								devorgv1.CRD_NAMESPACE: pinger.Namespace,
 							},
 						},
-						Spec: *constructPodSpec(pinger),
+						Spec: *constructPodSpec(pinger, defaults),
 					},
 				},
 			},
 		},
 	}
-	return cronjob
+	hash, err := cronJobSpecHash(cronjob.Spec)
+	if err != nil {
+		return nil, fmt.Errorf("unable to hash CronJob spec: %w", err)
+	}
+	cronjob.Annotations = map[string]string{
+		SpecHashAnnotation: hash,
+	}
+	return cronjob, nil
 }
 
-// TODO crd CoinbasePinger should contain desired PodSpec, so hardcoded values
-// should be replaced with values from CoinbasePinger spec or default one.
-func constructPodSpec(_pinger devorgv1.CoinbasePinger) *v1.PodSpec {
-	return &v1.PodSpec{
-		ServiceAccountName: "web-pinger-sa",
-		RestartPolicy:      v1.RestartPolicyNever,
+// Defaults below match the CRD ones, they apply to pingers stored before
+// the fields were added.
+
+func concurrencyPolicy(policy devorgv1.ConcurrencyPolicy) batchv1.ConcurrencyPolicy {
+	if policy == "" {
+		return batchv1.ForbidConcurrent
+	}
+	return batchv1.ConcurrencyPolicy(policy)
+}
+
+func int32OrDefault(value *int32, defaultValue int32) *int32 {
+	if value == nil {
+		return pointer.Int32Ptr(defaultValue)
+	}
+	return pointer.Int32Ptr(*value)
+}
+
+func int64OrDefault(value *int64, defaultValue int64) *int64 {
+	if value == nil {
+		return pointer.Int64Ptr(defaultValue)
+	}
+	return pointer.Int64Ptr(*value)
+}
+
+// pingerBaseURL returns the base URL the pinger calls.
+func pingerBaseURL(pinger devorgv1.CoinbasePinger, defaults configv1alpha1.PingerDefaults) string {
+	if pinger.Spec.BaseURL != "" {
+		return pinger.Spec.BaseURL
+	}
+	return defaults.BaseURL
+}
+
+func constructPodSpec(pinger devorgv1.CoinbasePinger, defaults configv1alpha1.PingerDefaults) *v1.PodSpec {
+	podSpec := &v1.PodSpec{
+		ServiceAccountName: serviceAccountName(pinger, defaults),
+		// the token is mounted into the pinger container only, see
+		// serviceAccountTokenVolumeSource
+		AutomountServiceAccountToken: pointer.BoolPtr(false),
+		SecurityContext:              podSecurityContext(pinger),
+		RestartPolicy:                v1.RestartPolicyNever,
 		Containers: []v1.Container{
 			v1.Container{
-				Name:    "pinger",
-				Image:   "kalynv/webapp-pinger",
-				Command: []string{"/webping"},
-				Args:    []string{"/prices/BTC-USD/buy"},
+				Name:            "pinger",
+				Image:           defaults.Image,
+				Command:         []string{"/webping"},
+				Args:            probeArgs(pinger),
+				Resources:       containerResources(pinger, defaults),
+				SecurityContext: containerSecurityContext(pinger),
+				// pinger writes its result here too, see podToCondition
+				TerminationMessagePath:   "/dev/termination-log",
+				TerminationMessagePolicy: v1.TerminationMessageReadFile,
 				Env: []v1.EnvVar{
 					v1.EnvVar{
 						Name:  "BASE_URL",
-						Value: "https://api.coinbase.com/v2",
+						Value: pingerBaseURL(pinger, defaults),
+					},
+					v1.EnvVar{
+						Name: "PINGER_UID",
+						ValueFrom: &v1.EnvVarSource{
+							FieldRef: &v1.ObjectFieldSelector{
+								FieldPath: fmt.Sprintf("metadata.labels['%s']", devorgv1.CRD_UID),
+							},
+						},
 					},
 				},
 				VolumeMounts: []v1.VolumeMount{
@@ -76,6 +146,11 @@ func constructPodSpec(_pinger devorgv1.CoinbasePinger) *v1.PodSpec {
 						ReadOnly:  true,
 						MountPath: "/etc/podinfo",
 					},
+					v1.VolumeMount{
+						Name:      serviceAccountTokenVolume,
+						ReadOnly:  true,
+						MountPath: serviceAccountTokenPath,
+					},
 				},
 			},
 		},
@@ -101,27 +176,45 @@ func constructPodSpec(_pinger devorgv1.CoinbasePinger) *v1.PodSpec {
 					},
 				},
 			},
+			v1.Volume{
+				Name:         serviceAccountTokenVolume,
+				VolumeSource: serviceAccountTokenVolumeSource(),
+			},
 		},
 	}
+	podSpec.Containers[0].Env = append(podSpec.Containers[0].Env, proxyEnv(pinger.Spec.Proxy)...)
+	return podSpec
+}
+
+// validateInterval reports intervals intervalToCrontabSchedule rejects
+// and intervals shorter than minInterval.
+func validateInterval(interval string, minInterval time.Duration) error {
+	if _, err := intervalToCrontabSchedule(interval); err != nil {
+		return fmt.Errorf("invalid interval %q: %w", interval, err)
+	}
+	if duration, _ := time.ParseDuration(interval); duration < minInterval {
+		return fmt.Errorf("invalid interval %q: must be at least %s", interval, minInterval)
+	}
+	return nil
 }
 
-func intervalToCrontabSchedule(interval string) (schedule string) {
+// intervalToCrontabSchedule converts an interval of at least a minute and
+// less than 24 hours to a crontab schedule.
+func intervalToCrontabSchedule(interval string) (string, error) {
 	duration, err := time.ParseDuration(interval)
 	if err != nil {
-		// duration must be parsable
-		panic(err.Error())
+		return "", err
 	}
 	minutes := int(duration.Minutes())
 	if minutes < 1 {
-		panic(fmt.Sprintf("Bad duration, must be at least a minute, but got %d minute", minutes))
+		return "", fmt.Errorf("Bad duration, must be at least a minute, but got %d minute", minutes)
 	}
 	if minutes < 60 {
-		return fmt.Sprintf("*/%d * * * *", minutes)
+		return fmt.Sprintf("*/%d * * * *", minutes), nil
 	}
 	hours := int(duration.Hours())
 	if hours < 24 {
-		return fmt.Sprintf("* */%d * * *", hours)
+		return fmt.Sprintf("* */%d * * *", hours), nil
 	}
-	// duration must be less than 24 hours
-	panic(fmt.Sprintf("Bad duration, must be less than 24 hours, but got %d hours", hours))
+	return "", fmt.Errorf("Bad duration, must be less than 24 hours, but got %d hours", hours)
 }
@@ -3,62 +3,58 @@ package controllers
 import (
 	"fmt"
 	"testing"
+	"time"
+
+	configv1alpha1 "github.com/kalynv/coinbase-pinger/operator/api/config/v1alpha1"
+	devorgv1 "github.com/kalynv/coinbase-pinger/operator/api/v1"
+	batchv1 "k8s.io/api/batch/v1"
 )
 
+// mustConstructCronJob constructs the CronJob of a pinger with a valid
+// interval.
+func mustConstructCronJob(
+	t *testing.T,
+	pinger devorgv1.CoinbasePinger,
+	name string,
+	defaults configv1alpha1.PingerDefaults,
+) *batchv1.CronJob {
+	t.Helper()
+	cronJob, err := constructCronJob(pinger, name, defaults)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cronJob
+}
+
 func Test_intervalToCrontabSchedule(t *testing.T) {
-	panicingTests := []struct {
-		name       string
-		interval   string
-		checkError func(panicString string, t *testing.T)
+	failingTests := []struct {
+		name     string
+		interval string
+		want     string
 	}{
 		{
 			name:     "unparsable duration",
 			interval: "unparsable",
-			checkError: func(value string, t *testing.T) {
-				expected := "time: invalid duration \"unparsable\""
-				if value != expected {
-					t.Errorf("Paniced with [%s], expected to panic with [%s]", value, expected)
-				}
-			},
+			want:     "time: invalid duration \"unparsable\"",
 		},
 		{
 			name:     "duration less than a minute",
 			interval: "59s",
-			checkError: func(value string, t *testing.T) {
-				expected := fmt.Sprintf("Bad duration, must be at least a minute, but got %d minute", 0)
-				if value != expected {
-					t.Errorf("Paniced with [%s], expected to panic with [%s]", value, expected)
-				}
-			},
+			want:     fmt.Sprintf("Bad duration, must be at least a minute, but got %d minute", 0),
 		},
 		{
 			name:     "duration bigger or equal 24 hours",
 			interval: "24h",
-			checkError: func(value string, t *testing.T) {
-				expected := fmt.Sprintf("Bad duration, must be less than 24 hours, but got %d hours", 24)
-				if value != expected {
-					t.Errorf("Paniced with [%s], expected to panic with [%s]", value, expected)
-				}
-			},
+			want:     fmt.Sprintf("Bad duration, must be less than 24 hours, but got %d hours", 24),
 		},
 	}
 
-	for _, tt := range panicingTests {
+	for _, tt := range failingTests {
 		t.Run(tt.name, func(t *testing.T) {
-			defer func() {
-				r := recover()
-				if r == nil {
-					t.Errorf("Expected to panic, but did not panic")
-					return
-				}
-				value, ok := r.(string)
-				if !ok {
-					t.Errorf("Got panic value type %T, want string", r)
-				}
-				tt.checkError(value, t)
-			}()
-
-			_ = intervalToCrontabSchedule(tt.interval)
+			_, err := intervalToCrontabSchedule(tt.interval)
+			if err == nil || err.Error() != tt.want {
+				t.Errorf("Got error [%v], want [%s]", err, tt.want)
+			}
 		})
 	}
 
@@ -91,10 +87,65 @@ func Test_intervalToCrontabSchedule(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := intervalToCrontabSchedule(tt.interval)
-			if got != tt.want {
+			got, err := intervalToCrontabSchedule(tt.interval)
+			if err != nil || got != tt.want {
 				t.Errorf("Got [%s], want [%s]", got, tt.want)
 			}
 		})
 	}
 }
+
+func Test_validateInterval(t *testing.T) {
+	if err := validateInterval("5m", time.Minute); err != nil {
+		t.Errorf("Got error [%v] for a valid interval", err)
+	}
+	err := validateInterval("59s", time.Minute)
+	expected := `invalid interval "59s": Bad duration, must be at least a minute, but got 0 minute`
+	if err == nil || err.Error() != expected {
+		t.Errorf("Got error [%v], want [%s]", err, expected)
+	}
+	err = validateInterval("5m", 10*time.Minute)
+	expected = `invalid interval "5m": must be at least 10m0s`
+	if err == nil || err.Error() != expected {
+		t.Errorf("Got error [%v], want [%s]", err, expected)
+	}
+}
+
+func Test_constructCronJob_badInterval(t *testing.T) {
+	pinger := devorgv1.CoinbasePinger{Spec: devorgv1.CoinbasePingerSpec{Interval: "24h"}}
+	_, err := constructCronJob(pinger, "pinger", DefaultOperatorConfig().Pinger)
+	expected := `invalid interval "24h": Bad duration, must be less than 24 hours, but got 24 hours`
+	if err == nil || err.Error() != expected {
+		t.Errorf("Got error [%v], want [%s]", err, expected)
+	}
+}
+
+func Test_constructCronJob_lifecycle(t *testing.T) {
+	pinger := devorgv1.CoinbasePinger{Spec: devorgv1.CoinbasePingerSpec{Interval: "5m"}}
+	defaults := mustConstructCronJob(t, pinger, "defaults", DefaultOperatorConfig().Pinger)
+	if *defaults.Spec.SuccessfulJobsHistoryLimit != 3 ||
+		*defaults.Spec.FailedJobsHistoryLimit != 1 ||
+		*defaults.Spec.StartingDeadlineSeconds != 60 ||
+		*defaults.Spec.JobTemplate.Spec.ActiveDeadlineSeconds != 120 ||
+		*defaults.Spec.JobTemplate.Spec.BackoffLimit != 0 ||
+		*defaults.Spec.JobTemplate.Spec.TTLSecondsAfterFinished != 86400 ||
+		defaults.Spec.ConcurrencyPolicy != batchv1.ForbidConcurrent {
+		t.Errorf("Got CronJob spec %+v, want defaults", defaults.Spec)
+	}
+
+	limit := int32(10)
+	deadline := int64(30)
+	pinger.Spec.SuccessfulJobsHistoryLimit = &limit
+	pinger.Spec.ActiveDeadlineSeconds = &deadline
+	pinger.Spec.ConcurrencyPolicy = "Replace"
+	custom := mustConstructCronJob(t, pinger, "custom", DefaultOperatorConfig().Pinger)
+	if *custom.Spec.SuccessfulJobsHistoryLimit != 10 ||
+		*custom.Spec.JobTemplate.Spec.ActiveDeadlineSeconds != 30 ||
+		custom.Spec.ConcurrencyPolicy != batchv1.ReplaceConcurrent {
+		t.Errorf("Got CronJob spec %+v, want spec values", custom.Spec)
+	}
+	limit = 0
+	if *custom.Spec.SuccessfulJobsHistoryLimit != 10 {
+		t.Errorf("CronJob spec shares pointers with the pinger spec")
+	}
+}
@@ -1,9 +1,35 @@
 package controllers
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+
 	batchv1 "k8s.io/api/batch/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 )
 
+// SpecHashAnnotation holds the hash of the desired CronJob spec the CronJob
+// was last written with.
+const SpecHashAnnotation = "batch.dev.org/spec-hash"
+
+// cronJobSpecHash hashes the whole desired CronJob spec: schedule, job
+// template, concurrency policy, history limits and suspend.
+func cronJobSpecHash(spec batchv1.CronJobSpec) (string, error) {
+	data, err := json.Marshal(spec)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:8]), nil
+}
+
+// cronjobChanged reports whether the CronJob has to be updated. It is true
+// when the desired spec changed since the last write, and when the CronJob
+// drifted away from it. Fields defaulted by the API server are ignored.
 func cronjobChanged(current, constructed *batchv1.CronJob) bool {
-	return current.Spec.Schedule != constructed.Spec.Schedule
+	if current.Annotations[SpecHashAnnotation] != constructed.Annotations[SpecHashAnnotation] {
+		return true
+	}
+	return !equality.Semantic.DeepDerivative(constructed.Spec, current.Spec)
 }
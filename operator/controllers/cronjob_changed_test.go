@@ -0,0 +1,109 @@
+package controllers
+
+import (
+	"testing"
+
+	devorgv1 "github.com/kalynv/coinbase-pinger/operator/api/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func Test_cronjobChanged(t *testing.T) {
+	pinger := devorgv1.CoinbasePinger{Spec: devorgv1.CoinbasePingerSpec{Interval: "5m"}}
+	suspend := true
+	historyLimit := int32(5)
+
+	tests := []struct {
+		name   string
+		mutate func(pinger *devorgv1.CoinbasePinger, current *batchv1.CronJob)
+		want   bool
+	}{
+		{
+			name:   "unchanged",
+			mutate: func(*devorgv1.CoinbasePinger, *batchv1.CronJob) {},
+		},
+		{
+			name: "fields defaulted by the API server",
+			mutate: func(_ *devorgv1.CoinbasePinger, current *batchv1.CronJob) {
+				current.Spec.JobTemplate.Spec.Template.Spec.DNSPolicy = corev1.DNSClusterFirst
+			},
+		},
+		{
+			name: "interval changed",
+			mutate: func(pinger *devorgv1.CoinbasePinger, _ *batchv1.CronJob) {
+				pinger.Spec.Interval = "10m"
+			},
+			want: true,
+		},
+		{
+			name: "suspended by hand",
+			mutate: func(_ *devorgv1.CoinbasePinger, current *batchv1.CronJob) {
+				current.Spec.Suspend = &suspend
+			},
+			want: true,
+		},
+		{
+			name: "history limit drifted",
+			mutate: func(_ *devorgv1.CoinbasePinger, current *batchv1.CronJob) {
+				current.Spec.FailedJobsHistoryLimit = &historyLimit
+			},
+			want: true,
+		},
+		{
+			name: "concurrency policy drifted",
+			mutate: func(_ *devorgv1.CoinbasePinger, current *batchv1.CronJob) {
+				current.Spec.ConcurrencyPolicy = batchv1.AllowConcurrent
+			},
+			want: true,
+		},
+		{
+			name: "image drifted",
+			mutate: func(_ *devorgv1.CoinbasePinger, current *batchv1.CronJob) {
+				current.Spec.JobTemplate.Spec.Template.Spec.Containers[0].Image = "other"
+			},
+			want: true,
+		},
+		{
+			name: "created before the hash annotation",
+			mutate: func(_ *devorgv1.CoinbasePinger, current *batchv1.CronJob) {
+				current.Annotations = nil
+			},
+			want: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			desired := pinger
+			current := mustConstructCronJob(t, desired, "sample-pinger", DefaultOperatorConfig().Pinger)
+			tt.mutate(&desired, current)
+			got := cronjobChanged(current, mustConstructCronJob(t, desired, "sample-pinger", DefaultOperatorConfig().Pinger))
+			if got != tt.want {
+				t.Errorf("Got changed %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_cronJobSpecHash(t *testing.T) {
+	spec := batchv1.CronJobSpec{Schedule: "*/5 * * * *"}
+	first, err := cronJobSpecHash(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := cronJobSpecHash(*spec.DeepCopy())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first != again {
+		t.Errorf("Got hash %s for the same spec, want %s", again, first)
+	}
+	spec.Schedule = "*/10 * * * *"
+	changed, err := cronJobSpecHash(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changed == first {
+		t.Errorf("Got hash %s for a changed schedule, want a new one", changed)
+	}
+}
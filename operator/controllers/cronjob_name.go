@@ -0,0 +1,58 @@
+package controllers
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"strings"
+
+	devorgv1 "github.com/kalynv/coinbase-pinger/operator/api/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// cronJobOwnerKey indexes CronJobs by the name of the controlling
+	// CoinbasePinger.
+	cronJobOwnerKey = ".metadata.controller"
+
+	// maxCronJobNameLength leaves room for the 11 characters the CronJob
+	// controller appends to Job names, which must fit a 63 character label.
+	maxCronJobNameLength = 52
+	cronJobNameSuffix    = "-pinger"
+)
+
+// cronJobName returns the readable `<pinger>-pinger` CronJob name. When
+// salt is set, e.g. to the pinger UID after a name collision, a short hash
+// of it is appended. Names which do not fit are truncated and always get
+// a hash of the full pinger name, so they stay unique.
+func cronJobName(pingerName string, salt string) string {
+	suffix := cronJobNameSuffix
+	if salt != "" {
+		suffix += "-" + shortHash(salt)
+	}
+	if len(pingerName)+len(suffix) <= maxCronJobNameLength {
+		return pingerName + suffix
+	}
+	suffix += "-" + shortHash(pingerName)
+	prefix := pingerName[:maxCronJobNameLength-len(suffix)]
+	return strings.TrimRight(prefix, "-.") + suffix
+}
+
+func shortHash(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return fmt.Sprintf("%x", sum[:3])
+}
+
+// indexCronJobOwner returns the name of the CoinbasePinger controlling the
+// CronJob, see cronJobOwnerKey.
+func indexCronJobOwner(obj client.Object) []string {
+	for _, owner := range obj.GetOwnerReferences() {
+		if owner.Controller == nil || !*owner.Controller {
+			continue
+		}
+		if owner.APIVersion != devorgv1.GroupVersion.String() || owner.Kind != "CoinbasePinger" {
+			return nil
+		}
+		return []string{owner.Name}
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package controllers
+
+import (
+	"strings"
+	"testing"
+
+	devorgv1 "github.com/kalynv/coinbase-pinger/operator/api/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+)
+
+func Test_cronJobName(t *testing.T) {
+	long := strings.Repeat("a", 40) + "-" + strings.Repeat("b", 40)
+
+	tests := []struct {
+		name       string
+		pingerName string
+		salt       string
+		want       string
+	}{
+		{
+			name:       "readable",
+			pingerName: "btc",
+			want:       "btc-pinger",
+		},
+		{
+			name:       "salted after collision",
+			pingerName: "btc",
+			salt:       "uid",
+			want:       "btc-pinger-" + shortHash("uid"),
+		},
+		{
+			name:       "truncated",
+			pingerName: long,
+			want:       strings.Repeat("a", 38) + "-pinger-" + shortHash(long),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := cronJobName(tt.pingerName, tt.salt)
+			if got != tt.want {
+				t.Errorf("Got [%s], want [%s]", got, tt.want)
+			}
+			if len(got) > maxCronJobNameLength {
+				t.Errorf("Got %d characters, want at most %d", len(got), maxCronJobNameLength)
+			}
+		})
+	}
+
+	if cronJobName(long, "") == cronJobName(long+"c", "") {
+		t.Errorf("Truncated names of different pingers are equal")
+	}
+	pinger := devorgv1.CoinbasePinger{Spec: devorgv1.CoinbasePingerSpec{Interval: "5m"}}
+	job := constructPingNowJob(pinger, mustConstructCronJob(t, pinger, cronJobName(long, "uid"), DefaultOperatorConfig().Pinger), "1")
+	if len(job.Name) > 63 {
+		t.Errorf("Got ping-now Job name of %d characters, want at most 63", len(job.Name))
+	}
+}
+
+func Test_indexCronJobOwner(t *testing.T) {
+	owner := func(apiVersion string, controller bool) []metav1.OwnerReference {
+		return []metav1.OwnerReference{{
+			APIVersion: apiVersion,
+			Kind:       "CoinbasePinger",
+			Name:       "btc",
+			Controller: pointer.BoolPtr(controller),
+		}}
+	}
+
+	tests := []struct {
+		name   string
+		owners []metav1.OwnerReference
+		want   []string
+	}{
+		{
+			name:   "controlled by a pinger",
+			owners: owner(devorgv1.GroupVersion.String(), true),
+			want:   []string{"btc"},
+		},
+		{
+			name:   "owned without controller reference",
+			owners: owner(devorgv1.GroupVersion.String(), false),
+		},
+		{
+			name:   "controlled by something else",
+			owners: owner("other/v1", true),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cronJob := &batchv1.CronJob{}
+			cronJob.OwnerReferences = tt.owners
+			got := indexCronJobOwner(cronJob)
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("Got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
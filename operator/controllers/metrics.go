@@ -0,0 +1,53 @@
+package controllers
+
+import (
+	devorgv1 "github.com/kalynv/coinbase-pinger/operator/api/v1"
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+var (
+	pingerUp = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "coinbasepinger_up",
+			Help: "Whether the newest ping of the CoinbasePinger succeeded.",
+		},
+		[]string{"namespace", "name"},
+	)
+	pingerLastPing = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "coinbasepinger_last_ping_timestamp_seconds",
+			Help: "Time of the newest ping of the CoinbasePinger.",
+		},
+		[]string{"namespace", "name"},
+	)
+)
+
+func init() {
+	metrics.Registry.MustRegister(pingerUp, pingerLastPing)
+}
+
+// recordMetrics exports the newest ping result of the pinger.
+func recordMetrics(pinger devorgv1.CoinbasePinger, conditions []devorgv1.Condition) {
+	var newest *devorgv1.Condition
+	for i := range conditions {
+		if newest == nil || newest.PingTime.Before(&conditions[i].PingTime) {
+			newest = &conditions[i]
+		}
+	}
+	if newest == nil {
+		return
+	}
+	up := 0.0
+	if newest.Status {
+		up = 1
+	}
+	pingerUp.WithLabelValues(pinger.Namespace, pinger.Name).Set(up)
+	pingerLastPing.WithLabelValues(pinger.Namespace, pinger.Name).Set(float64(newest.PingTime.Unix()))
+}
+
+// deleteMetrics stops exporting metrics of a removed pinger.
+func deleteMetrics(pinger devorgv1.CoinbasePinger) {
+	pingerUp.DeleteLabelValues(pinger.Namespace, pinger.Name)
+	pingerLastPing.DeleteLabelValues(pinger.Namespace, pinger.Name)
+}
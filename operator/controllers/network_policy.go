@@ -0,0 +1,270 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	configv1alpha1 "github.com/kalynv/coinbase-pinger/operator/api/config/v1alpha1"
+	devorgv1 "github.com/kalynv/coinbase-pinger/operator/api/v1"
+)
+
+// egressPeer is an address range pinger pods connect to, on port.
+type egressPeer struct {
+	cidr string
+	port int32
+}
+
+// reconcileNetworkPolicy creates or updates the NetworkPolicy limiting the
+// egress of the pinger pods, or removes it when the operator config does
+// not ask for one. The target host, or the proxy the pinger reaches it
+// through, is resolved on every reconcile, so the policy follows address
+// changes with the next ping at the latest.
+func (r *CoinbasePingerReconciler) reconcileNetworkPolicy(
+	ctx context.Context,
+	pinger *devorgv1.CoinbasePinger,
+	config *configv1alpha1.OperatorConfig,
+	defaults configv1alpha1.PingerDefaults,
+) error {
+	l := log.FromContext(ctx)
+	name := cronJobName(pinger.Name, "")
+	if !config.Egress.NetworkPolicy {
+		policy := &networkingv1.NetworkPolicy{}
+		err := r.Get(ctx, types.NamespacedName{Name: name, Namespace: pinger.Namespace}, policy)
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		if err != nil || !metav1.IsControlledBy(policy, pinger) {
+			return err
+		}
+		l.Info("removing NetworkPolicy", "NetworkPolicy", name)
+		return client.IgnoreNotFound(r.Delete(ctx, policy))
+	}
+
+	targets, err := r.pingerPeers(ctx, pinger, defaults)
+	if err != nil {
+		// keep the previous policy, the target may resolve again later
+		l.Error(err, "unable to resolve the pinger target")
+		r.Recorder.Event(pinger, corev1.EventTypeWarning, "NetworkPolicyNotUpdated", err.Error())
+		return nil
+	}
+	apiServers, err := r.apiServerPeers(ctx, config)
+	if err != nil {
+		return err
+	}
+
+	policy := &networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: pinger.Namespace},
+	}
+	_, err = r.ensureOwned(ctx, pinger, policy, func() {
+		policy.Spec = networkPolicySpec(pinger, config.Egress.DNSCIDRs, append(targets, apiServers...))
+	})
+	var notControlled *notControlledError
+	if errors.As(err, &notControlled) {
+		r.Recorder.Event(pinger, corev1.EventTypeWarning, "NetworkPolicyNotUpdated", err.Error())
+		return nil
+	}
+	return err
+}
+
+// networkPolicySpec selects the pods of the pinger and allows their egress
+// to the cluster DNS, the DNS servers in dnsCIDRs and to the peers only.
+func networkPolicySpec(
+	pinger *devorgv1.CoinbasePinger,
+	dnsCIDRs []string,
+	peers []egressPeer,
+) networkingv1.NetworkPolicySpec {
+	udp := corev1.ProtocolUDP
+	tcp := corev1.ProtocolTCP
+	dnsPort := intstr.FromInt(53)
+	dns := []networkingv1.NetworkPolicyPeer{
+		{
+			NamespaceSelector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{"kubernetes.io/metadata.name": metav1.NamespaceSystem},
+			},
+			PodSelector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{"k8s-app": "kube-dns"},
+			},
+		},
+	}
+	for _, cidr := range dnsCIDRs {
+		dns = append(dns, networkingv1.NetworkPolicyPeer{IPBlock: &networkingv1.IPBlock{CIDR: cidr}})
+	}
+	rules := []networkingv1.NetworkPolicyEgressRule{
+		{
+			To: dns,
+			Ports: []networkingv1.NetworkPolicyPort{
+				{Protocol: &udp, Port: &dnsPort},
+				{Protocol: &tcp, Port: &dnsPort},
+			},
+		},
+	}
+	for _, peer := range peers {
+		port := intstr.FromInt(int(peer.port))
+		rules = append(rules, networkingv1.NetworkPolicyEgressRule{
+			To: []networkingv1.NetworkPolicyPeer{
+				{IPBlock: &networkingv1.IPBlock{CIDR: peer.cidr}},
+			},
+			Ports: []networkingv1.NetworkPolicyPort{
+				{Protocol: &tcp, Port: &port},
+			},
+		})
+	}
+	return networkingv1.NetworkPolicySpec{
+		PodSelector: metav1.LabelSelector{
+			MatchLabels: map[string]string{devorgv1.CRD_UID: string(pinger.UID)},
+		},
+		PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeEgress},
+		Egress:      rules,
+	}
+}
+
+// pingerPeers returns the proxy of probes which use one, and the target
+// when the pinger reaches it directly.
+func (r *CoinbasePingerReconciler) pingerPeers(
+	ctx context.Context,
+	pinger *devorgv1.CoinbasePinger,
+	defaults configv1alpha1.PingerDefaults,
+) ([]egressPeer, error) {
+	var peers []egressPeer
+	target, err := pingerTarget(*pinger, defaults)
+	if err != nil || target == "" {
+		return nil, err
+	}
+	proxy := pinger.Spec.Proxy
+	if !usesProxy(probeType(*pinger)) {
+		proxy = nil
+	}
+	if proxy != nil {
+		proxyPeers, err := r.targetPeers(ctx, proxy.URL)
+		if err != nil {
+			return nil, err
+		}
+		peers = append(peers, proxyPeers...)
+	}
+	if !proxyBypassed(proxy, target) {
+		return peers, nil
+	}
+	targets, err := r.targetPeers(ctx, target)
+	if err != nil {
+		return nil, err
+	}
+	return append(peers, targets...), nil
+}
+
+// targetPeers resolves the host of the base URL to single address peers,
+// sorted so that the policy does not change with the resolver order.
+func (r *CoinbasePingerReconciler) targetPeers(ctx context.Context, baseURL string) ([]egressPeer, error) {
+	parsed, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, err
+	}
+	port, err := urlPort(parsed)
+	if err != nil {
+		return nil, err
+	}
+	lookupIP := r.lookupIP
+	if lookupIP == nil {
+		lookupIP = func(ctx context.Context, host string) ([]net.IP, error) {
+			return net.DefaultResolver.LookupIP(ctx, "ip", host)
+		}
+	}
+	ips, err := lookupIP(ctx, parsed.Hostname())
+	if err != nil {
+		return nil, err
+	}
+	var peers []egressPeer
+	for _, ip := range ips {
+		peers = append(peers, egressPeer{cidr: hostCIDR(ip), port: port})
+	}
+	sort.Slice(peers, func(i, j int) bool { return peers[i].cidr < peers[j].cidr })
+	return peers, nil
+}
+
+// apiServerPeers returns where pinger pods reach the API server. Policies
+// may apply after Service translation, so the endpoints of the kubernetes
+// Service are allowed besides its cluster IP.
+func (r *CoinbasePingerReconciler) apiServerPeers(
+	ctx context.Context,
+	config *configv1alpha1.OperatorConfig,
+) ([]egressPeer, error) {
+	var peers []egressPeer
+	if len(config.Egress.APIServerCIDRs) > 0 {
+		for _, cidr := range config.Egress.APIServerCIDRs {
+			peers = append(peers, egressPeer{cidr: cidr, port: 443}, egressPeer{cidr: cidr, port: 6443})
+		}
+		return peers, nil
+	}
+
+	if ip := net.ParseIP(os.Getenv("KUBERNETES_SERVICE_HOST")); ip != nil {
+		port, _ := strconv.Atoi(os.Getenv("KUBERNETES_SERVICE_PORT"))
+		if port == 0 {
+			port = 443
+		}
+		peers = append(peers, egressPeer{cidr: hostCIDR(ip), port: int32(port)})
+	}
+	reader := r.APIReader
+	if reader == nil {
+		reader = r.Client
+	}
+	endpoints := &corev1.Endpoints{}
+	err := reader.Get(ctx, types.NamespacedName{Name: "kubernetes", Namespace: metav1.NamespaceDefault}, endpoints)
+	if apierrors.IsForbidden(err) && len(peers) > 0 {
+		// namespaced deployments may not read the default namespace
+		log.FromContext(ctx).Info("unable to read the API server endpoints, set egress.apiServerCIDRs")
+		return peers, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to find the API server endpoints: %w", err)
+	}
+	for _, subset := range endpoints.Subsets {
+		for _, address := range subset.Addresses {
+			ip := net.ParseIP(address.IP)
+			if ip == nil {
+				continue
+			}
+			for _, port := range subset.Ports {
+				peers = append(peers, egressPeer{cidr: hostCIDR(ip), port: port.Port})
+			}
+		}
+	}
+	return peers, nil
+}
+
+func urlPort(u *url.URL) (int32, error) {
+	if u.Port() != "" {
+		port, err := strconv.ParseUint(u.Port(), 10, 16)
+		return int32(port), err
+	}
+	switch u.Scheme {
+	case "http", "ws":
+		return 80, nil
+	case "https", "wss":
+		return 443, nil
+	case "socks5":
+		return 1080, nil
+	}
+	return 0, fmt.Errorf("no port for scheme %q of %s", u.Scheme, u)
+}
+
+// hostCIDR returns the single address CIDR of ip.
+func hostCIDR(ip net.IP) string {
+	if ip.To4() != nil {
+		return ip.String() + "/32"
+	}
+	return ip.String() + "/128"
+}
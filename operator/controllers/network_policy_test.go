@@ -0,0 +1,110 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/url"
+	"os"
+	"testing"
+
+	devorgv1 "github.com/kalynv/coinbase-pinger/operator/api/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func Test_reconcileNetworkPolicy(t *testing.T) {
+	ctx := context.Background()
+	pinger := &devorgv1.CoinbasePinger{
+		ObjectMeta: metav1.ObjectMeta{Name: "btc", Namespace: "team", UID: types.UID("uid")},
+		Spec:       devorgv1.CoinbasePingerSpec{BaseURL: "https://api.example.org:8443/v2"},
+	}
+	apiServer := &corev1.Endpoints{
+		ObjectMeta: metav1.ObjectMeta{Name: "kubernetes", Namespace: "default"},
+		Subsets: []corev1.EndpointSubset{{
+			Addresses: []corev1.EndpointAddress{{IP: "172.18.0.2"}},
+			Ports:     []corev1.EndpointPort{{Port: 6443}},
+		}},
+	}
+	// the cluster IP is taken from the environment inside a cluster
+	if host, found := os.LookupEnv("KUBERNETES_SERVICE_HOST"); found {
+		os.Unsetenv("KUBERNETES_SERVICE_HOST")
+		defer os.Setenv("KUBERNETES_SERVICE_HOST", host)
+	}
+	r := newRBACTestReconciler(t, pinger, apiServer)
+	r.lookupIP = func(ctx context.Context, host string) ([]net.IP, error) {
+		if host != "api.example.org" {
+			return nil, errors.New("unexpected host " + host)
+		}
+		return []net.IP{net.ParseIP("2001:db8::1"), net.ParseIP("192.0.2.10")}, nil
+	}
+	config := DefaultOperatorConfig()
+	config.Egress.NetworkPolicy = true
+	config.Egress.DNSCIDRs = []string{"169.254.20.10/32"}
+	defaults := config.Pinger
+
+	if err := r.reconcileNetworkPolicy(ctx, pinger, config, defaults); err != nil {
+		t.Fatal(err)
+	}
+	policy := &networkingv1.NetworkPolicy{}
+	key := types.NamespacedName{Name: "btc-pinger", Namespace: "team"}
+	if err := r.Get(ctx, key, policy); err != nil {
+		t.Fatal(err)
+	}
+	if !metav1.IsControlledBy(policy, pinger) || policy.Spec.PodSelector.MatchLabels[devorgv1.CRD_UID] != "uid" {
+		t.Errorf("Got NetworkPolicy %+v, want it controlled by the pinger and selecting its pods", policy.ObjectMeta)
+	}
+	var got []string
+	for _, rule := range policy.Spec.Egress[1:] {
+		got = append(got, rule.To[0].IPBlock.CIDR+":"+rule.Ports[0].Port.String())
+	}
+	expected := []string{"192.0.2.10/32:8443", "2001:db8::1/128:8443", "172.18.0.2/32:6443"}
+	if len(got) != len(expected) {
+		t.Fatalf("Got egress %v, want %v", got, expected)
+	}
+	for i := range expected {
+		if got[i] != expected[i] {
+			t.Errorf("Got egress %v, want %v", got, expected)
+			break
+		}
+	}
+	dns := policy.Spec.Egress[0]
+	if len(dns.To) != 2 || dns.Ports[0].Port.IntValue() != 53 {
+		t.Fatalf("Got first egress rule %+v, want DNS", dns)
+	}
+	if kubeDNS := dns.To[0]; kubeDNS.NamespaceSelector == nil || kubeDNS.PodSelector == nil ||
+		kubeDNS.NamespaceSelector.MatchLabels["kubernetes.io/metadata.name"] != "kube-system" ||
+		kubeDNS.PodSelector.MatchLabels["k8s-app"] != "kube-dns" {
+		t.Errorf("Got DNS peer %+v, want the kube-dns pods", kubeDNS)
+	}
+	if nodeLocal := dns.To[1]; nodeLocal.IPBlock == nil || nodeLocal.IPBlock.CIDR != "169.254.20.10/32" {
+		t.Errorf("Got DNS peer %+v, want [169.254.20.10/32]", nodeLocal)
+	}
+
+	config.Egress.NetworkPolicy = false
+	if err := r.reconcileNetworkPolicy(ctx, pinger, config, defaults); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Get(ctx, key, policy); !apierrors.IsNotFound(err) {
+		t.Errorf("Got error [%v], want the NetworkPolicy removed", err)
+	}
+}
+
+func Test_urlPort(t *testing.T) {
+	tests := map[string]int32{
+		"https://api.coinbase.com/v2":         443,
+		"http://10.0.0.1/v2":                  80,
+		"wss://ws-feed.exchange.coinbase.com": 443,
+		"http://localhost:8080":               8080,
+	}
+	for rawURL, expected := range tests {
+		parsed, _ := url.Parse(rawURL)
+		port, err := urlPort(parsed)
+		if err != nil || port != expected {
+			t.Errorf("Got port %d and error [%v] for %s, want %d", port, err, rawURL, expected)
+		}
+	}
+}
@@ -0,0 +1,256 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	configv1alpha1 "github.com/kalynv/coinbase-pinger/operator/api/config/v1alpha1"
+)
+
+// DefaultOperatorConfig returns the config used when no config file is
+// given, and for the fields a config file leaves empty.
+func DefaultOperatorConfig() *configv1alpha1.OperatorConfig {
+	return &configv1alpha1.OperatorConfig{
+		Pinger: configv1alpha1.PingerDefaults{
+			Image:   "kalynv/webapp-pinger",
+			BaseURL: "https://api.coinbase.com/v2",
+			Resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{
+					corev1.ResourceCPU:    resource.MustParse("10m"),
+					corev1.ResourceMemory: resource.MustParse("16Mi"),
+				},
+				Limits: corev1.ResourceList{
+					corev1.ResourceCPU:    resource.MustParse("100m"),
+					corev1.ResourceMemory: resource.MustParse("64Mi"),
+				},
+			},
+		},
+		RequeueAfter: metav1.Duration{Duration: time.Second * 10},
+		MinInterval:  metav1.Duration{Duration: time.Minute},
+	}
+}
+
+// ConfigStore holds the operator config loaded from a file and reloads it
+// when the file changes.
+type ConfigStore struct {
+	path    string
+	decoder runtime.Decoder
+
+	mu     sync.RWMutex
+	config *configv1alpha1.OperatorConfig
+}
+
+// NewConfigStore loads the operator config file at path. The scheme must
+// contain the config types.
+func NewConfigStore(path string, scheme *runtime.Scheme) (*ConfigStore, error) {
+	s := &ConfigStore{
+		path:    path,
+		decoder: serializer.NewCodecFactory(scheme).UniversalDecoder(),
+	}
+	if _, err := s.Reload(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Get returns the current config, which must not be modified. A nil store
+// returns the defaults.
+func (s *ConfigStore) Get() *configv1alpha1.OperatorConfig {
+	if s == nil {
+		return DefaultOperatorConfig()
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.config
+}
+
+// Reload reads the config file again and reports whether the config
+// changed. An invalid file keeps the previous config.
+func (s *ConfigStore) Reload() (bool, error) {
+	data, err := ioutil.ReadFile(s.path)
+	if err != nil {
+		return false, err
+	}
+	config := &configv1alpha1.OperatorConfig{}
+	if err := runtime.DecodeInto(s.decoder, data, config); err != nil {
+		return false, fmt.Errorf("could not decode operator config %s: %w", s.path, err)
+	}
+	applyConfigDefaults(config)
+	if err := validateOperatorConfig(config); err != nil {
+		return false, fmt.Errorf("invalid operator config %s: %w", s.path, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.config != nil && equality.Semantic.DeepEqual(s.config, config) {
+		return false, nil
+	}
+	s.config = config
+	return true, nil
+}
+
+// Watch reloads the config on changes of the file until ctx is done and
+// calls onChange after the config changed. The directory is watched, as
+// ConfigMap volumes replace files by swapping a symlink.
+func (s *ConfigStore) Watch(ctx context.Context, onChange func()) error {
+	l := log.FromContext(ctx).WithValues("config", s.path)
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+	if err := watcher.Add(filepath.Dir(s.path)); err != nil {
+		return err
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case _, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			changed, err := s.Reload()
+			if err != nil {
+				l.Error(err, "keeping the previous operator config")
+				continue
+			}
+			if changed {
+				l.Info("operator config reloaded")
+				onChange()
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			l.Error(err, "watching operator config")
+		}
+	}
+}
+
+func applyConfigDefaults(config *configv1alpha1.OperatorConfig) {
+	defaults := DefaultOperatorConfig()
+	config.Pinger = mergePingerDefaults(defaults.Pinger, config.Pinger)
+	if config.RequeueAfter.Duration <= 0 {
+		config.RequeueAfter = defaults.RequeueAfter
+	}
+	if config.MinInterval.Duration <= 0 {
+		config.MinInterval = defaults.MinInterval
+	}
+}
+
+func validateOperatorConfig(config *configv1alpha1.OperatorConfig) error {
+	for _, cidrs := range [][]string{config.Egress.AllowedCIDRs, config.Egress.APIServerCIDRs, config.Egress.DNSCIDRs} {
+		for _, cidr := range cidrs {
+			if _, _, err := net.ParseCIDR(cidr); err != nil {
+				return err
+			}
+		}
+	}
+	if err := baseURLAllowed(config, config.Pinger.BaseURL); err != nil {
+		return err
+	}
+	for namespace, override := range config.NamespaceOverrides {
+		if override.BaseURL == "" {
+			continue
+		}
+		if err := baseURLAllowed(config, override.BaseURL); err != nil {
+			return fmt.Errorf("namespace %s: %w", namespace, err)
+		}
+	}
+	return nil
+}
+
+// pingerDefaults returns the pinger defaults with the overrides of the
+// namespace applied.
+func pingerDefaults(config *configv1alpha1.OperatorConfig, namespace string) configv1alpha1.PingerDefaults {
+	override, found := config.NamespaceOverrides[namespace]
+	if !found {
+		return config.Pinger
+	}
+	return mergePingerDefaults(config.Pinger, override)
+}
+
+// mergePingerDefaults replaces the fields of defaults set in override.
+func mergePingerDefaults(defaults, override configv1alpha1.PingerDefaults) configv1alpha1.PingerDefaults {
+	merged := *defaults.DeepCopy()
+	if override.Image != "" {
+		merged.Image = override.Image
+	}
+	if override.ServiceAccountName != "" {
+		merged.ServiceAccountName = override.ServiceAccountName
+	}
+	if override.BaseURL != "" {
+		merged.BaseURL = override.BaseURL
+	}
+	if len(override.Resources.Limits) > 0 || len(override.Resources.Requests) > 0 {
+		merged.Resources = *override.Resources.DeepCopy()
+	}
+	return merged
+}
+
+// baseURLAllowed reports base URLs which do not parse or whose host is not
+// allowed: an address must be in one of the allowed CIDRs, a name must be
+// one of the allowed domains, their subdomains or match an allowed host
+// pattern. Everything is allowed without allowlists.
+func baseURLAllowed(config *configv1alpha1.OperatorConfig, baseURL string) error {
+	parsed, err := url.Parse(baseURL)
+	if err != nil {
+		return fmt.Errorf("invalid base URL %q: %w", baseURL, err)
+	}
+	host := parsed.Hostname()
+	if parsed.Scheme == "" || host == "" {
+		return fmt.Errorf("invalid base URL %q: scheme and host are required", baseURL)
+	}
+	egress := config.Egress
+	if len(config.AllowedBaseURLDomains) == 0 && len(egress.AllowedHosts) == 0 && len(egress.AllowedCIDRs) == 0 {
+		return nil
+	}
+
+	if ip := net.ParseIP(host); ip != nil {
+		for _, cidr := range egress.AllowedCIDRs {
+			if _, ipNet, err := net.ParseCIDR(cidr); err == nil && ipNet.Contains(ip) {
+				return nil
+			}
+		}
+		return fmt.Errorf("address %s of base URL %q is not in an allowed CIDR", host, baseURL)
+	}
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	for _, domain := range config.AllowedBaseURLDomains {
+		domain = strings.ToLower(strings.TrimPrefix(domain, "."))
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return nil
+		}
+	}
+	for _, pattern := range egress.AllowedHosts {
+		if hostMatches(strings.ToLower(pattern), host) {
+			return nil
+		}
+	}
+	return fmt.Errorf("host %q of base URL %q is not allowed", host, baseURL)
+}
+
+// hostMatches matches host against a host name or a `*.` subdomain
+// pattern.
+func hostMatches(pattern string, host string) bool {
+	if strings.HasPrefix(pattern, "*.") {
+		return strings.HasSuffix(host, pattern[1:])
+	}
+	return host == pattern
+}
@@ -0,0 +1,199 @@
+package controllers
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	configv1alpha1 "github.com/kalynv/coinbase-pinger/operator/api/config/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const testOperatorConfig = `apiVersion: config.dev.org/v1alpha1
+kind: OperatorConfig
+leaderElection:
+  leaderElect: true
+pinger:
+  image: example.org/pinger:v1
+  resources:
+    limits:
+      memory: 64Mi
+namespaceOverrides:
+  sandbox:
+    baseURL: https://sandbox.coinbase.com/v2
+    serviceAccountName: sandbox-pinger
+allowedBaseURLDomains:
+- coinbase.com
+minInterval: 5m
+`
+
+func writeOperatorConfig(t *testing.T, path string, content string) {
+	t.Helper()
+	if err := ioutil.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func newTestConfigStore(t *testing.T, content string) (*ConfigStore, string) {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	if err := configv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	dir, err := ioutil.TempDir("", "operator-config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	path := filepath.Join(dir, "controller_manager_config.yaml")
+	writeOperatorConfig(t, path, content)
+	store, err := NewConfigStore(path, scheme)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store, path
+}
+
+func Test_ConfigStore_load(t *testing.T) {
+	store, _ := newTestConfigStore(t, testOperatorConfig)
+	config := store.Get()
+
+	if config.LeaderElection == nil || config.LeaderElection.LeaderElect == nil || !*config.LeaderElection.LeaderElect {
+		t.Errorf("Got leader election %+v, want the manager config to be loaded", config.LeaderElection)
+	}
+	if config.RequeueAfter.Duration != 10*time.Second || config.MinInterval.Duration != 5*time.Minute {
+		t.Errorf("Got requeue after %s and min interval %s", config.RequeueAfter.Duration, config.MinInterval.Duration)
+	}
+
+	defaults := pingerDefaults(config, "default")
+	if defaults.Image != "example.org/pinger:v1" ||
+		defaults.ServiceAccountName != "" ||
+		defaults.BaseURL != "https://api.coinbase.com/v2" {
+		t.Errorf("Got defaults %+v", defaults)
+	}
+	if !defaults.Resources.Limits[corev1.ResourceMemory].Equal(resource.MustParse("64Mi")) {
+		t.Errorf("Got resources %+v", defaults.Resources)
+	}
+
+	sandbox := pingerDefaults(config, "sandbox")
+	if sandbox.Image != "example.org/pinger:v1" ||
+		sandbox.ServiceAccountName != "sandbox-pinger" ||
+		sandbox.BaseURL != "https://sandbox.coinbase.com/v2" {
+		t.Errorf("Got sandbox defaults %+v", sandbox)
+	}
+}
+
+func Test_NewConfigStore_invalid(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := configv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	dir, err := ioutil.TempDir("", "operator-config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "config.yaml")
+	writeOperatorConfig(t, path, testOperatorConfig+"  - example.org\n")
+	if _, err := NewConfigStore(path, scheme); err == nil {
+		t.Errorf("Got no error for an unparsable config")
+	}
+	writeOperatorConfig(t, path, `apiVersion: config.dev.org/v1alpha1
+kind: OperatorConfig
+pinger:
+  baseURL: https://example.org
+allowedBaseURLDomains:
+- coinbase.com
+`)
+	if _, err := NewConfigStore(path, scheme); err == nil {
+		t.Errorf("Got no error for a default base URL which is not allowed")
+	}
+}
+
+func Test_ConfigStore_nil(t *testing.T) {
+	var store *ConfigStore
+	if store.Get().Pinger.Image != "kalynv/webapp-pinger" {
+		t.Errorf("Got config %+v, want defaults", store.Get())
+	}
+}
+
+func Test_baseURLAllowed(t *testing.T) {
+	config := &configv1alpha1.OperatorConfig{AllowedBaseURLDomains: []string{"coinbase.com"}}
+	tests := []struct {
+		baseURL string
+		allowed bool
+	}{
+		{"https://coinbase.com/v2", true},
+		{"https://api.coinbase.com/v2", true},
+		{"https://API.Coinbase.com:443/v2", true},
+		{"https://notcoinbase.com/v2", false},
+		{"https://coinbase.com.example.org/v2", false},
+		{"api.coinbase.com/v2", false},
+	}
+	for _, tt := range tests {
+		err := baseURLAllowed(config, tt.baseURL)
+		if (err == nil) != tt.allowed {
+			t.Errorf("Got error [%v] for %s, want allowed %t", err, tt.baseURL, tt.allowed)
+		}
+	}
+	config.Egress = configv1alpha1.EgressConfig{
+		AllowedHosts: []string{"status.example.org", "*.feeds.example.org"},
+		AllowedCIDRs: []string{"10.1.0.0/16"},
+	}
+	tests = append(tests, []struct {
+		baseURL string
+		allowed bool
+	}{
+		{"https://status.example.org/health", true},
+		{"https://ws.feeds.example.org", true},
+		{"https://feeds.example.org", false},
+		{"https://other.example.org", false},
+		{"http://10.1.2.3:8080/v2", true},
+		{"http://10.2.0.1/v2", false},
+		{"http://[fd00::1]/v2", false},
+		{"http://169.254.169.254/latest/meta-data", false},
+	}...)
+	for _, tt := range tests {
+		err := baseURLAllowed(config, tt.baseURL)
+		if (err == nil) != tt.allowed {
+			t.Errorf("Got error [%v] for %s with egress allowlists, want allowed %t", err, tt.baseURL, tt.allowed)
+		}
+	}
+	if err := baseURLAllowed(&configv1alpha1.OperatorConfig{}, "https://example.org"); err != nil {
+		t.Errorf("Got error [%v], want all hosts allowed without allowed domains", err)
+	}
+}
+
+func Test_ConfigStore_Watch(t *testing.T) {
+	store, path := newTestConfigStore(t, testOperatorConfig)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	changed := make(chan struct{}, 10)
+	done := make(chan error)
+	go func() {
+		done <- store.Watch(ctx, func() { changed <- struct{}{} })
+	}()
+
+	// the watch may start after the first write, so write until noticed
+	updated := testOperatorConfig + "requeueAfter: 30s\n"
+	deadline := time.After(10 * time.Second)
+	for store.Get().RequeueAfter.Duration != 30*time.Second {
+		writeOperatorConfig(t, path, updated)
+		select {
+		case <-changed:
+		case <-time.After(100 * time.Millisecond):
+		case <-deadline:
+			t.Fatalf("Config was not reloaded")
+		}
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("Got error [%v] from Watch", err)
+	}
+}
@@ -0,0 +1,65 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	devorgv1 "github.com/kalynv/coinbase-pinger/operator/api/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func Test_patchStatus(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := devorgv1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	stored := &devorgv1.CoinbasePinger{
+		ObjectMeta: metav1.ObjectMeta{Name: "btc", Namespace: "default"},
+		Status:     devorgv1.CoinbasePingerStatus{LastHandledPingNow: "1"},
+	}
+	r := &CoinbasePingerReconciler{
+		Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(stored).Build(),
+		Scheme: scheme,
+	}
+
+	pinger := &devorgv1.CoinbasePinger{}
+	if err := r.Get(ctx, client.ObjectKeyFromObject(stored), pinger); err != nil {
+		t.Fatal(err)
+	}
+	stale := pinger.DeepCopy()
+
+	version := pinger.ResourceVersion
+	err := r.patchStatus(ctx, pinger, func(status *devorgv1.CoinbasePingerStatus) {
+		status.LastHandledPingNow = "1"
+	})
+	if err != nil || pinger.ResourceVersion != version {
+		t.Errorf("Unchanged status was written: error %v, resourceVersion %s -> %s",
+			err, version, pinger.ResourceVersion)
+	}
+
+	err = r.patchStatus(ctx, pinger, func(status *devorgv1.CoinbasePingerStatus) {
+		status.LastHandledPingNow = "2"
+	})
+	if err != nil || pinger.ResourceVersion == version {
+		t.Errorf("Changed status was not written: error %v", err)
+	}
+
+	err = r.patchStatus(ctx, stale, func(status *devorgv1.CoinbasePingerStatus) {
+		status.ObservedGeneration = 3
+	})
+	if err != nil {
+		t.Fatalf("Conflict was not retried: %v", err)
+
+	}
+	got := &devorgv1.CoinbasePinger{}
+	if err := r.Get(ctx, client.ObjectKeyFromObject(stored), got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Status.LastHandledPingNow != "2" || got.Status.ObservedGeneration != 3 {
+		t.Errorf("Got status %+v, want both patches applied", got.Status)
+	}
+}
@@ -0,0 +1,62 @@
+package controllers
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"strings"
+
+	devorgv1 "github.com/kalynv/coinbase-pinger/operator/api/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// maxPingNowPrefixLength leaves room for the "-now-" and the nonce hash.
+const maxPingNowPrefixLength = 50
+
+// pendingPingNow returns the ping-now nonce which has not been handled yet.
+func pendingPingNow(pinger devorgv1.CoinbasePinger) (nonce string, pending bool) {
+	nonce = pinger.GetAnnotations()[devorgv1.PingNowAnnotation]
+	if nonce == "" || nonce == pinger.Status.LastHandledPingNow {
+		return "", false
+	}
+	return nonce, true
+}
+
+// constructPingNowJob builds a one-off Job from the CronJob JobTemplate, so
+// pods it runs are labelled and reported the same way as scheduled ones.
+// Job name is derived from the nonce, so the same nonce never runs twice.
+func constructPingNowJob(
+	pinger devorgv1.CoinbasePinger,
+	cronJob *batchv1.CronJob,
+	nonce string,
+) *batchv1.Job {
+	nonceHash := sha256.Sum256([]byte(nonce))
+	// Job names are used as labels, so they must fit 63 characters
+	prefix := cronJob.Name
+	if len(prefix) > maxPingNowPrefixLength {
+		prefix = strings.TrimRight(prefix[:maxPingNowPrefixLength], "-.")
+	}
+	template := cronJob.Spec.JobTemplate.DeepCopy()
+
+	labels := map[string]string{}
+	for k, v := range template.Labels {
+		labels[k] = v
+	}
+	labels[devorgv1.CRD_UID] = string(pinger.UID)
+
+	annotations := map[string]string{}
+	for k, v := range template.Annotations {
+		annotations[k] = v
+	}
+	annotations[devorgv1.PingNowAnnotation] = nonce
+
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        fmt.Sprintf("%s-now-%x", prefix, nonceHash[:4]),
+			Namespace:   pinger.Namespace,
+			Labels:      labels,
+			Annotations: annotations,
+		},
+		Spec: template.Spec,
+	}
+}
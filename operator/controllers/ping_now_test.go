@@ -0,0 +1,78 @@
+package controllers
+
+import (
+	"testing"
+
+	devorgv1 "github.com/kalynv/coinbase-pinger/operator/api/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func Test_pendingPingNow(t *testing.T) {
+	tests := []struct {
+		name        string
+		annotation  string
+		handled     string
+		wantNonce   string
+		wantPending bool
+	}{
+		{
+			name: "no annotation",
+		},
+		{
+			name:       "already handled",
+			annotation: "1",
+			handled:    "1",
+		},
+		{
+			name:        "new nonce",
+			annotation:  "2",
+			handled:     "1",
+			wantNonce:   "2",
+			wantPending: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pinger := devorgv1.CoinbasePinger{}
+			if tt.annotation != "" {
+				pinger.Annotations = map[string]string{
+					devorgv1.PingNowAnnotation: tt.annotation,
+				}
+			}
+			pinger.Status.LastHandledPingNow = tt.handled
+
+			nonce, pending := pendingPingNow(pinger)
+			if nonce != tt.wantNonce || pending != tt.wantPending {
+				t.Errorf("Got (%q, %v), want (%q, %v)", nonce, pending, tt.wantNonce, tt.wantPending)
+			}
+		})
+	}
+}
+
+func Test_constructPingNowJob(t *testing.T) {
+	pinger := devorgv1.CoinbasePinger{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "sample",
+			Namespace: "default",
+			UID:       "uid",
+		},
+		Spec: devorgv1.CoinbasePingerSpec{Interval: "5m"},
+	}
+	cronJob := mustConstructCronJob(t, pinger, cronJobName(pinger.Name, ""), DefaultOperatorConfig().Pinger)
+
+	first := constructPingNowJob(pinger, cronJob, "1")
+	again := constructPingNowJob(pinger, cronJob, "1")
+	second := constructPingNowJob(pinger, cronJob, "2")
+
+	if first.Name != again.Name {
+		t.Errorf("Same nonce produced different Job names [%s] and [%s]", first.Name, again.Name)
+	}
+	if first.Name == second.Name {
+		t.Errorf("Different nonces produced the same Job name [%s]", first.Name)
+	}
+	podLabels := first.Spec.Template.Labels
+	if podLabels[devorgv1.CRD_UID] != string(pinger.UID) {
+		t.Errorf("Got pod label %s=[%s], want [%s]", devorgv1.CRD_UID, podLabels[devorgv1.CRD_UID], pinger.UID)
+	}
+}
@@ -0,0 +1,287 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	configv1alpha1 "github.com/kalynv/coinbase-pinger/operator/api/config/v1alpha1"
+	devorgv1 "github.com/kalynv/coinbase-pinger/operator/api/v1"
+)
+
+// pingerPodRules are the permissions pinger pods need, they report their
+// result to their own pod, see probe.PodSink. Job pod names are random and
+// only known once the pod exists, so the rules can not be limited to
+// resource names and apply to all pods of the namespace. They are kept to
+// patch alone: the result is merge patched into the labels and annotations,
+// so pinger pods can neither read other pods nor replace them.
+var pingerPodRules = []rbacv1.PolicyRule{
+	{
+		APIGroups: []string{""},
+		Resources: []string{"pods"},
+		Verbs:     []string{"patch"},
+	},
+}
+
+// permissionCheckTTL is how long the permissions of a ServiceAccount are
+// trusted. Grants outside of the pinger RBAC show up in the status after
+// this at the latest.
+const permissionCheckTTL = 10 * time.Minute
+
+// permissionCache remembers the permissions missingPermissions found per
+// ServiceAccount, so that reconciles do not each ask the API server for
+// every verb. The zero value is ready to use.
+type permissionCache struct {
+	mu      sync.Mutex
+	entries map[types.NamespacedName]permissionCheck
+}
+
+type permissionCheck struct {
+	missing []string
+	checked time.Time
+}
+
+// get returns the missing permissions of the ServiceAccount checked less
+// than permissionCheckTTL before now.
+func (c *permissionCache) get(key types.NamespacedName, now time.Time) ([]string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	check, found := c.entries[key]
+	if !found || now.Sub(check.checked) >= permissionCheckTTL {
+		return nil, false
+	}
+	return check.missing, true
+}
+
+// put remembers the missing permissions of the ServiceAccount and drops
+// expired entries, like those of removed pingers.
+func (c *permissionCache) put(key types.NamespacedName, missing []string, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries == nil {
+		c.entries = map[types.NamespacedName]permissionCheck{}
+	}
+	for other, check := range c.entries {
+		if now.Sub(check.checked) >= permissionCheckTTL {
+			delete(c.entries, other)
+		}
+	}
+	c.entries[key] = permissionCheck{missing: missing, checked: now}
+}
+
+// forget drops the ServiceAccount, its permissions changed.
+func (c *permissionCache) forget(key types.NamespacedName) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, key)
+}
+
+// serviceAccountName returns the ServiceAccount pinger pods run as. The
+// operator config may name an existing one, otherwise the pinger gets its
+// own, see ensurePingerRBAC.
+func serviceAccountName(pinger devorgv1.CoinbasePinger, defaults configv1alpha1.PingerDefaults) string {
+	if defaults.ServiceAccountName != "" {
+		return defaults.ServiceAccountName
+	}
+	return cronJobName(pinger.Name, "")
+}
+
+// ensurePingerRBAC creates or updates the ServiceAccount, Role and
+// RoleBinding of the pinger. They are controlled by the pinger, so they are
+// garbage collected with it. Objects of the same name which belong to
+// someone else are left alone and reported. Creating or changing any of
+// them drops the checked permissions of the ServiceAccount.
+func (r *CoinbasePingerReconciler) ensurePingerRBAC(
+	ctx context.Context,
+	pinger *devorgv1.CoinbasePinger,
+	name string,
+) error {
+	objectMeta := metav1.ObjectMeta{
+		Name:      name,
+		Namespace: pinger.Namespace,
+	}
+	ensure := func(obj client.Object, mutate func()) error {
+		result, err := r.ensureOwned(ctx, pinger, obj, mutate)
+		if result != controllerutil.OperationResultNone {
+			r.permissions.forget(types.NamespacedName{Name: name, Namespace: pinger.Namespace})
+		}
+		return err
+	}
+	serviceAccount := &corev1.ServiceAccount{ObjectMeta: objectMeta}
+	if err := ensure(serviceAccount, func() {}); err != nil {
+		return err
+	}
+	role := &rbacv1.Role{ObjectMeta: objectMeta}
+	err := ensure(role, func() {
+		role.Rules = pingerPodRules
+	})
+	if err != nil {
+		return err
+	}
+	roleBinding := &rbacv1.RoleBinding{ObjectMeta: objectMeta}
+	return ensure(roleBinding, func() {
+		roleBinding.RoleRef = rbacv1.RoleRef{
+			APIGroup: rbacv1.GroupName,
+			Kind:     "Role",
+			Name:     name,
+		}
+		roleBinding.Subjects = []rbacv1.Subject{
+			{
+				Kind:      rbacv1.ServiceAccountKind,
+				Name:      name,
+				Namespace: pinger.Namespace,
+			},
+		}
+	})
+}
+
+// ensureOwned creates or updates obj with the fields set by mutate. The
+// devorgv1.CRD_UID label selects the object into the cache, see NewCache, so an
+// unlabelled object of the same name looks missing and only its creation
+// fails. It belongs to someone else either way.
+func (r *CoinbasePingerReconciler) ensureOwned(
+	ctx context.Context,
+	pinger *devorgv1.CoinbasePinger,
+	obj client.Object,
+	mutate func(),
+) (controllerutil.OperationResult, error) {
+	result, err := controllerutil.CreateOrUpdate(ctx, r.Client, obj, func() error {
+		if obj.GetResourceVersion() != "" && !metav1.IsControlledBy(obj, pinger) {
+			return &notControlledError{kind: reflect.TypeOf(obj).Elem().Name(), name: obj.GetName()}
+		}
+		labels := obj.GetLabels()
+		if labels == nil {
+			labels = map[string]string{}
+		}
+		labels[devorgv1.CRD_UID] = string(pinger.UID)
+		obj.SetLabels(labels)
+		mutate()
+		return controllerutil.SetControllerReference(pinger, obj, r.Scheme)
+	})
+	if apierrors.IsAlreadyExists(err) {
+		return result, &notControlledError{kind: reflect.TypeOf(obj).Elem().Name(), name: obj.GetName()}
+	}
+	if result != controllerutil.OperationResultNone {
+		log.FromContext(ctx).Info(
+			"provisioned pinger RBAC",
+			"kind", reflect.TypeOf(obj).Elem().Name(),
+			"name", obj.GetName(),
+			"operation", result,
+		)
+	}
+	return result, err
+}
+
+// missingPermissions asks the API server which of pingerPodRules the
+// ServiceAccount lacks in the pinger namespace. The answers are formatted
+// like `patch pods`.
+func (r *CoinbasePingerReconciler) missingPermissions(
+	ctx context.Context,
+	pinger *devorgv1.CoinbasePinger,
+	serviceAccount string,
+) ([]string, error) {
+	var missing []string
+	user := fmt.Sprintf("system:serviceaccount:%s:%s", pinger.Namespace, serviceAccount)
+	for _, rule := range pingerPodRules {
+		for _, resource := range rule.Resources {
+			for _, verb := range rule.Verbs {
+				review := &authorizationv1.LocalSubjectAccessReview{
+					ObjectMeta: metav1.ObjectMeta{Namespace: pinger.Namespace},
+					Spec: authorizationv1.SubjectAccessReviewSpec{
+						User:   user,
+						Groups: []string{"system:serviceaccounts", "system:serviceaccounts:" + pinger.Namespace},
+						ResourceAttributes: &authorizationv1.ResourceAttributes{
+							Namespace: pinger.Namespace,
+							Verb:      verb,
+							Group:     rule.APIGroups[0],
+							Resource:  resource,
+						},
+					},
+				}
+				if err := r.Create(ctx, review); err != nil {
+					return nil, err
+				}
+				if !review.Status.Allowed {
+					missing = append(missing, verb+" "+resource)
+				}
+			}
+		}
+	}
+	return missing, nil
+}
+
+// checkPermissions provisions the pinger RBAC and records permissions the
+// pinger pods lack in the status. Failing to provision or check is not
+// fatal, the pinger may still work with permissions granted otherwise. A
+// ServiceAccount with all permissions is checked again only once the
+// pinger RBAC changed or permissionCheckTTL passed. Missing permissions are
+// not remembered, the authorizer may not have seen a new RoleBinding yet.
+func (r *CoinbasePingerReconciler) checkPermissions(
+	ctx context.Context,
+	pinger *devorgv1.CoinbasePinger,
+	defaults configv1alpha1.PingerDefaults,
+) error {
+	l := log.FromContext(ctx)
+	serviceAccount := serviceAccountName(*pinger, defaults)
+	if defaults.ServiceAccountName == "" {
+		err := r.ensurePingerRBAC(ctx, pinger, serviceAccount)
+		var notControlled *notControlledError
+		if err != nil && !apierrors.IsForbidden(err) && !errors.As(err, &notControlled) {
+			return err
+		}
+		if err != nil {
+			l.Error(err, "unable to provision pinger RBAC")
+			r.Recorder.Event(pinger, corev1.EventTypeWarning, "RBACNotProvisioned", err.Error())
+		}
+	}
+
+	key := types.NamespacedName{Name: serviceAccount, Namespace: pinger.Namespace}
+	now := time.Now()
+	missing, found := r.permissions.get(key, now)
+	if !found {
+		var err error
+		missing, err = r.missingPermissions(ctx, pinger, serviceAccount)
+		if apierrors.IsForbidden(err) {
+			l.Error(err, "unable to check pinger permissions")
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if len(missing) == 0 {
+			r.permissions.put(key, missing, now)
+		}
+	}
+	if len(missing) > 0 && !equality.Semantic.DeepEqual(missing, pinger.Status.MissingPermissions) {
+		r.Recorder.Eventf(pinger, corev1.EventTypeWarning, "MissingPermissions",
+			"ServiceAccount %s lacks permissions: %v", serviceAccount, missing)
+	}
+	return r.patchStatus(ctx, pinger, func(status *devorgv1.CoinbasePingerStatus) {
+		status.MissingPermissions = missing
+	})
+}
+
+// notControlledError reports an object named like the pinger RBAC which
+// belongs to someone else.
+type notControlledError struct {
+	kind string
+	name string
+}
+
+func (e *notControlledError) Error() string {
+	return fmt.Sprintf("%s %s exists and is not controlled by the pinger", e.kind, e.name)
+}
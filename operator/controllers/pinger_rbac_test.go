@@ -0,0 +1,212 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	devorgv1 "github.com/kalynv/coinbase-pinger/operator/api/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func newRBACTestReconciler(t *testing.T, objects ...client.Object) *CoinbasePingerReconciler {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := devorgv1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	return &CoinbasePingerReconciler{
+		Client:   fake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).Build(),
+		Scheme:   scheme,
+		Recorder: record.NewFakeRecorder(10),
+	}
+}
+
+func Test_ensurePingerRBAC(t *testing.T) {
+	ctx := context.Background()
+	pinger := &devorgv1.CoinbasePinger{
+		ObjectMeta: metav1.ObjectMeta{Name: "btc", Namespace: "team", UID: types.UID("uid")},
+	}
+	r := newRBACTestReconciler(t, pinger)
+	name := serviceAccountName(*pinger, DefaultOperatorConfig().Pinger)
+	if name != "btc-pinger" {
+		t.Errorf("Got ServiceAccount name %s, want btc-pinger", name)
+	}
+
+	if err := r.ensurePingerRBAC(ctx, pinger, name); err != nil {
+		t.Fatal(err)
+	}
+	key := types.NamespacedName{Name: name, Namespace: "team"}
+	for _, obj := range []client.Object{&corev1.ServiceAccount{}, &rbacv1.Role{}, &rbacv1.RoleBinding{}} {
+		if err := r.Get(ctx, key, obj); err != nil {
+			t.Fatal(err)
+		}
+		if !metav1.IsControlledBy(obj, pinger) || obj.GetLabels()[devorgv1.CRD_UID] != "uid" {
+			t.Errorf("Got %T owners %v and labels %v, want it controlled and labelled", obj, obj.GetOwnerReferences(), obj.GetLabels())
+		}
+	}
+
+	role := &rbacv1.Role{}
+	if err := r.Get(ctx, key, role); err != nil {
+		t.Fatal(err)
+	}
+	role.Rules = append(role.Rules, rbacv1.PolicyRule{
+		APIGroups: []string{""}, Resources: []string{"secrets"}, Verbs: []string{"get"},
+	})
+	if err := r.Update(ctx, role); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.ensurePingerRBAC(ctx, pinger, name); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Get(ctx, key, role); err != nil {
+		t.Fatal(err)
+	}
+	if len(role.Rules) != 1 || role.Rules[0].Resources[0] != "pods" {
+		t.Errorf("Got rules %v, want the widened Role to be reverted", role.Rules)
+	}
+}
+
+func Test_ensurePingerRBAC_notControlled(t *testing.T) {
+	pinger := &devorgv1.CoinbasePinger{
+		ObjectMeta: metav1.ObjectMeta{Name: "btc", Namespace: "team", UID: types.UID("uid")},
+	}
+	foreign := &corev1.ServiceAccount{
+		ObjectMeta: metav1.ObjectMeta{Name: "btc-pinger", Namespace: "team"},
+	}
+	r := newRBACTestReconciler(t, pinger, foreign)
+
+	err := r.ensurePingerRBAC(context.Background(), pinger, "btc-pinger")
+	expected := "ServiceAccount btc-pinger exists and is not controlled by the pinger"
+	if err == nil || err.Error() != expected {
+		t.Errorf("Got error [%v], want [%s]", err, expected)
+	}
+}
+
+// labelFilteredClient reads like the manager cache, which only holds
+// objects labelled with devorgv1.CRD_UID, see NewCache.
+type labelFilteredClient struct {
+	client.Client
+}
+
+func (c labelFilteredClient) Get(ctx context.Context, key client.ObjectKey, obj client.Object) error {
+	if err := c.Client.Get(ctx, key, obj); err != nil {
+		return err
+	}
+	if _, found := obj.GetLabels()[devorgv1.CRD_UID]; !found {
+		return apierrors.NewNotFound(corev1.Resource("serviceaccounts"), key.Name)
+	}
+	return nil
+}
+
+func Test_ensurePingerRBAC_notControlledUncached(t *testing.T) {
+	pinger := &devorgv1.CoinbasePinger{
+		ObjectMeta: metav1.ObjectMeta{Name: "btc", Namespace: "team", UID: types.UID("uid")},
+	}
+	foreign := &corev1.ServiceAccount{
+		ObjectMeta: metav1.ObjectMeta{Name: "btc-pinger", Namespace: "team"},
+	}
+	r := newRBACTestReconciler(t, pinger, foreign)
+	r.Client = labelFilteredClient{Client: r.Client}
+
+	err := r.ensurePingerRBAC(context.Background(), pinger, "btc-pinger")
+	expected := "ServiceAccount btc-pinger exists and is not controlled by the pinger"
+	if err == nil || err.Error() != expected {
+		t.Errorf("Got error [%v], want [%s]", err, expected)
+	}
+}
+
+// reviewCountingClient answers LocalSubjectAccessReviews, which the fake
+// client can not, allowing everything unless deny is set.
+type reviewCountingClient struct {
+	client.Client
+	reviews int
+	deny    bool
+}
+
+func (c *reviewCountingClient) Create(ctx context.Context, obj client.Object, opts ...client.CreateOption) error {
+	if review, ok := obj.(*authorizationv1.LocalSubjectAccessReview); ok {
+		c.reviews++
+		review.Status.Allowed = !c.deny
+		return nil
+	}
+	return c.Client.Create(ctx, obj, opts...)
+}
+
+func Test_checkPermissions_cached(t *testing.T) {
+	ctx := context.Background()
+	pinger := &devorgv1.CoinbasePinger{
+		ObjectMeta: metav1.ObjectMeta{Name: "btc", Namespace: "team", UID: types.UID("uid")},
+	}
+	r := newRBACTestReconciler(t, pinger)
+	counting := &reviewCountingClient{Client: r.Client}
+	r.Client = counting
+	defaults := DefaultOperatorConfig().Pinger
+	key := types.NamespacedName{Name: "btc-pinger", Namespace: "team"}
+
+	steps := []struct {
+		name     string
+		before   func()
+		expected int
+	}{
+		{"first check", func() {}, 1},
+		{"unchanged RBAC", func() {}, 1},
+		{"Role removed", func() {
+			if err := r.Delete(ctx, &rbacv1.Role{ObjectMeta: metav1.ObjectMeta{Name: key.Name, Namespace: key.Namespace}}); err != nil {
+				t.Fatal(err)
+			}
+		}, 2},
+		{"expired check", func() {
+			check := r.permissions.entries[key]
+			check.checked = check.checked.Add(-permissionCheckTTL)
+			r.permissions.entries[key] = check
+		}, 3},
+	}
+	for _, step := range steps {
+		step.before()
+		if err := r.checkPermissions(ctx, pinger, defaults); err != nil {
+			t.Fatal(err)
+		}
+		if counting.reviews != step.expected {
+			t.Errorf("Got %d reviews after %s, want %d", counting.reviews, step.name, step.expected)
+		}
+	}
+}
+
+func Test_checkPermissions_missingNotCached(t *testing.T) {
+	ctx := context.Background()
+	pinger := &devorgv1.CoinbasePinger{
+		ObjectMeta: metav1.ObjectMeta{Name: "btc", Namespace: "team", UID: types.UID("uid")},
+	}
+	r := newRBACTestReconciler(t, pinger)
+	denying := &reviewCountingClient{Client: r.Client, deny: true}
+	r.Client = denying
+	defaults := DefaultOperatorConfig().Pinger
+
+	for i := 0; i < 2; i++ {
+		if err := r.checkPermissions(ctx, pinger, defaults); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if denying.reviews != 2 {
+		t.Errorf("Got %d reviews, want 2", denying.reviews)
+	}
+	if err := r.Get(ctx, client.ObjectKeyFromObject(pinger), pinger); err != nil {
+		t.Fatal(err)
+	}
+	if len(pinger.Status.MissingPermissions) != 1 || pinger.Status.MissingPermissions[0] != "patch pods" {
+		t.Errorf("Got missing permissions %v, want [patch pods]", pinger.Status.MissingPermissions)
+	}
+}
@@ -0,0 +1,102 @@
+package controllers
+
+import (
+	"fmt"
+	"time"
+
+	devorgv1 "github.com/kalynv/coinbase-pinger/operator/api/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// maxPendingTimeout is how long a pinger pod may stay Pending at most
+// before it is reported as an infrastructure failure.
+const maxPendingTimeout = 5 * time.Minute
+
+// pendingTimeout returns how long pods of the pinger may stay Pending. The
+// Job controller deletes the pods of a Job past its active deadline, so it
+// is half the deadline at most, leaving time to report them before.
+func pendingTimeout(pinger devorgv1.CoinbasePinger) time.Duration {
+	deadline := time.Duration(*int64OrDefault(pinger.Spec.ActiveDeadlineSeconds, defaultActiveDeadlineSeconds))
+	if timeout := deadline * time.Second / 2; timeout < maxPendingTimeout {
+		return timeout
+	}
+	return maxPendingTimeout
+}
+
+// waitingFailures are container waiting reasons the pinger never recovers
+// from without outside help.
+var waitingFailures = map[string]bool{
+	"ErrImagePull":               true,
+	"ImagePullBackOff":           true,
+	"InvalidImageName":           true,
+	"CreateContainerConfigError": true,
+	"CreateContainerError":       true,
+	"CrashLoopBackOff":           true,
+}
+
+// podFailureCondition classifies pinger pods which failed for reasons
+// unrelated to the pinged service. Pods Pending for longer than
+// maxPending count as failed. It returns false for pods which are
+// still running the ping.
+func podFailureCondition(pod corev1.Pod, now time.Time, maxPending time.Duration) (devorgv1.Condition, bool) {
+	failure := func(reason, message string, at metav1.Time) (devorgv1.Condition, bool) {
+		if message != "" {
+			reason = reason + ": " + message
+		}
+		return devorgv1.Condition{
+			Type:     devorgv1.ServiceUnknown,
+			Status:   false,
+			Reason:   devorgv1.ProbeInfrastructureFailure,
+			Message:  reason,
+			PingTime: at,
+		}, true
+	}
+
+	// Evicted and DeadlineExceeded are set on the pod, not on containers
+	if pod.Status.Reason != "" && pod.Status.Phase == corev1.PodFailed {
+		return failure(pod.Status.Reason, pod.Status.Message, pod.CreationTimestamp)
+	}
+
+	for _, status := range pod.Status.ContainerStatuses {
+		if waiting := status.State.Waiting; waiting != nil && waitingFailures[waiting.Reason] {
+			return failure(waiting.Reason, waiting.Message, pod.CreationTimestamp)
+		}
+		if terminated := status.State.Terminated; terminated != nil && terminated.ExitCode != 0 {
+			reason := terminated.Reason
+			if reason == "" {
+				reason = "Error"
+			}
+			return failure(
+				reason,
+				fmt.Sprintf("container %s exited with code %d", status.Name, terminated.ExitCode),
+				terminated.FinishedAt,
+			)
+		}
+	}
+
+	switch pod.Status.Phase {
+	case corev1.PodPending:
+		if now.Sub(pod.CreationTimestamp.Time) > maxPending {
+			return failure(
+				"PendingTooLong",
+				fmt.Sprintf("pod is pending for more than %s", maxPending),
+				pod.CreationTimestamp,
+			)
+		}
+	case corev1.PodFailed:
+		return failure("PodFailed", pod.Status.Message, pod.CreationTimestamp)
+	case corev1.PodSucceeded:
+		return failure("NoResult", "pinger completed without reporting a result", pod.CreationTimestamp)
+	}
+	return devorgv1.Condition{}, false
+}
+
+func hasPendingPods(pods []corev1.Pod) bool {
+	for _, pod := range pods {
+		if pod.Status.Phase == corev1.PodPending {
+			return true
+		}
+	}
+	return false
+}
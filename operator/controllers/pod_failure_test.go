@@ -0,0 +1,144 @@
+package controllers
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	devorgv1 "github.com/kalynv/coinbase-pinger/operator/api/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func Test_podFailureCondition(t *testing.T) {
+	now := time.Date(2021, 9, 1, 12, 0, 0, 0, time.UTC)
+	maxPending := pendingTimeout(devorgv1.CoinbasePinger{})
+	created := metav1.NewTime(now.Add(-time.Minute))
+	containerState := func(state corev1.ContainerState) []corev1.ContainerStatus {
+		return []corev1.ContainerStatus{{Name: "pinger", State: state}}
+	}
+
+	tests := []struct {
+		name        string
+		status      corev1.PodStatus
+		created     metav1.Time
+		wantFailed  bool
+		wantMessage string
+	}{
+		{
+			name: "image pull back-off",
+			status: corev1.PodStatus{
+				Phase: corev1.PodPending,
+				ContainerStatuses: containerState(corev1.ContainerState{
+					Waiting: &corev1.ContainerStateWaiting{Reason: "ImagePullBackOff"},
+				}),
+			},
+			wantFailed:  true,
+			wantMessage: "ImagePullBackOff",
+		},
+		{
+			name: "crash loop",
+			status: corev1.PodStatus{
+				Phase: corev1.PodRunning,
+				ContainerStatuses: containerState(corev1.ContainerState{
+					Waiting: &corev1.ContainerStateWaiting{Reason: "CrashLoopBackOff"},
+				}),
+			},
+			wantFailed:  true,
+			wantMessage: "CrashLoopBackOff",
+		},
+		{
+			name: "OOM killed",
+			status: corev1.PodStatus{
+				Phase: corev1.PodFailed,
+				ContainerStatuses: containerState(corev1.ContainerState{
+					Terminated: &corev1.ContainerStateTerminated{Reason: "OOMKilled", ExitCode: 137},
+				}),
+			},
+			wantFailed:  true,
+			wantMessage: "OOMKilled",
+		},
+		{
+			name: "evicted",
+			status: corev1.PodStatus{
+				Phase:   corev1.PodFailed,
+				Reason:  "Evicted",
+				Message: "The node was low on resource: memory.",
+			},
+			wantFailed:  true,
+			wantMessage: "Evicted",
+		},
+		{
+			name: "deadline exceeded",
+			status: corev1.PodStatus{
+				Phase:  corev1.PodFailed,
+				Reason: "DeadlineExceeded",
+			},
+			wantFailed:  true,
+			wantMessage: "DeadlineExceeded",
+		},
+		{
+			name:        "pending too long",
+			status:      corev1.PodStatus{Phase: corev1.PodPending},
+			created:     metav1.NewTime(now.Add(-maxPending - time.Second)),
+			wantFailed:  true,
+			wantMessage: "PendingTooLong",
+		},
+		{
+			name:    "recently pending",
+			status:  corev1.PodStatus{Phase: corev1.PodPending},
+			created: metav1.NewTime(now.Add(-maxPending + time.Second)),
+		},
+		{
+			name: "running",
+			status: corev1.PodStatus{
+				Phase: corev1.PodRunning,
+				ContainerStatuses: containerState(corev1.ContainerState{
+					Running: &corev1.ContainerStateRunning{},
+				}),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pod := corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{CreationTimestamp: created},
+				Status:     tt.status,
+			}
+			if !tt.created.IsZero() {
+				pod.CreationTimestamp = tt.created
+			}
+
+			condition, failed := podFailureCondition(pod, now, maxPending)
+			if failed != tt.wantFailed {
+				t.Fatalf("Got failed %v, want %v", failed, tt.wantFailed)
+			}
+			if !failed {
+				return
+			}
+			if condition.Reason != devorgv1.ProbeInfrastructureFailure || condition.Status {
+				t.Errorf("Got reason [%s] status %v, want [%s] false",
+					condition.Reason, condition.Status, devorgv1.ProbeInfrastructureFailure)
+			}
+			if !strings.HasPrefix(condition.Message, tt.wantMessage) {
+				t.Errorf("Got message [%s], want prefix [%s]", condition.Message, tt.wantMessage)
+			}
+		})
+	}
+}
+
+func Test_pendingTimeout(t *testing.T) {
+	pinger := devorgv1.CoinbasePinger{Spec: devorgv1.CoinbasePingerSpec{Interval: "5m"}}
+	deadline := time.Duration(*mustConstructCronJob(t, pinger, "btc-pinger", DefaultOperatorConfig().Pinger).
+		Spec.JobTemplate.Spec.ActiveDeadlineSeconds) * time.Second
+	if timeout := pendingTimeout(pinger); timeout != time.Minute || timeout >= deadline {
+		t.Errorf("Got pending timeout %s for the default deadline %s, want 1m0s", timeout, deadline)
+	}
+
+	long := int64(3600)
+	pinger.Spec.ActiveDeadlineSeconds = &long
+	if timeout := pendingTimeout(pinger); timeout != maxPendingTimeout {
+		t.Errorf("Got pending timeout %s for a long deadline, want %s", timeout, maxPendingTimeout)
+	}
+}
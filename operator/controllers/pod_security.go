@@ -0,0 +1,101 @@
+package controllers
+
+import (
+	configv1alpha1 "github.com/kalynv/coinbase-pinger/operator/api/config/v1alpha1"
+	devorgv1 "github.com/kalynv/coinbase-pinger/operator/api/v1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/utils/pointer"
+)
+
+const (
+	// pingerUserID is the non-root user of the pinger image, see
+	// app/Dockerfile. It is set on the pod too, so images built before the
+	// user was added still run.
+	pingerUserID = 65532
+
+	// serviceAccountTokenVolume replaces the auto-mounted token with a
+	// short-lived one, only mounted into the pinger container.
+	serviceAccountTokenVolume = "kube-api-access"
+	// serviceAccountTokenPath is where rest.InClusterConfig reads the token,
+	// CA and namespace from.
+	serviceAccountTokenPath = "/var/run/secrets/kubernetes.io/serviceaccount"
+	// serviceAccountTokenExpiration is the shortest the API server allows,
+	// the kubelet rotates the token before it expires.
+	serviceAccountTokenExpiration = 600
+)
+
+// podSecurityContext returns the pod security context of the pinger, which
+// passes the restricted Pod Security Standard unless the spec replaces it.
+func podSecurityContext(pinger devorgv1.CoinbasePinger) *v1.PodSecurityContext {
+	if pinger.Spec.PodSecurityContext != nil {
+		return pinger.Spec.PodSecurityContext.DeepCopy()
+	}
+	return &v1.PodSecurityContext{
+		RunAsNonRoot: pointer.BoolPtr(true),
+		RunAsUser:    pointer.Int64Ptr(pingerUserID),
+		RunAsGroup:   pointer.Int64Ptr(pingerUserID),
+		SeccompProfile: &v1.SeccompProfile{
+			Type: v1.SeccompProfileTypeRuntimeDefault,
+		},
+	}
+}
+
+// containerSecurityContext returns the security context of the pinger
+// container, see podSecurityContext.
+func containerSecurityContext(pinger devorgv1.CoinbasePinger) *v1.SecurityContext {
+	if pinger.Spec.SecurityContext != nil {
+		return pinger.Spec.SecurityContext.DeepCopy()
+	}
+	return &v1.SecurityContext{
+		RunAsNonRoot:             pointer.BoolPtr(true),
+		AllowPrivilegeEscalation: pointer.BoolPtr(false),
+		ReadOnlyRootFilesystem:   pointer.BoolPtr(true),
+		Capabilities: &v1.Capabilities{
+			Drop: []v1.Capability{"ALL"},
+		},
+	}
+}
+
+// containerResources returns the resources of the pinger container.
+func containerResources(
+	pinger devorgv1.CoinbasePinger,
+	defaults configv1alpha1.PingerDefaults,
+) v1.ResourceRequirements {
+	if pinger.Spec.Resources != nil {
+		return *pinger.Spec.Resources.DeepCopy()
+	}
+	return *defaults.Resources.DeepCopy()
+}
+
+// serviceAccountTokenVolumeSource projects what the auto-mounted token
+// volume contains, with a shorter token lifetime.
+func serviceAccountTokenVolumeSource() v1.VolumeSource {
+	return v1.VolumeSource{
+		Projected: &v1.ProjectedVolumeSource{
+			Sources: []v1.VolumeProjection{
+				{
+					ServiceAccountToken: &v1.ServiceAccountTokenProjection{
+						Path:              "token",
+						ExpirationSeconds: pointer.Int64Ptr(serviceAccountTokenExpiration),
+					},
+				},
+				{
+					ConfigMap: &v1.ConfigMapProjection{
+						LocalObjectReference: v1.LocalObjectReference{Name: "kube-root-ca.crt"},
+						Items:                []v1.KeyToPath{{Key: "ca.crt", Path: "ca.crt"}},
+					},
+				},
+				{
+					DownwardAPI: &v1.DownwardAPIProjection{
+						Items: []v1.DownwardAPIVolumeFile{
+							{
+								Path:     "namespace",
+								FieldRef: &v1.ObjectFieldSelector{FieldPath: "metadata.namespace"},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
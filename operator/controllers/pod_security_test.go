@@ -0,0 +1,81 @@
+package controllers
+
+import (
+	"testing"
+
+	devorgv1 "github.com/kalynv/coinbase-pinger/operator/api/v1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	psapi "k8s.io/pod-security-admission/api"
+	"k8s.io/pod-security-admission/policy"
+	"k8s.io/utils/pointer"
+)
+
+// restrictedViolations evaluates the pod template of the pinger CronJob
+// against the latest restricted Pod Security Standard.
+func restrictedViolations(t *testing.T, pinger devorgv1.CoinbasePinger) []string {
+	t.Helper()
+	evaluator, err := policy.NewEvaluator(policy.DefaultChecks())
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := mustConstructCronJob(t, pinger, "pinger", DefaultOperatorConfig().Pinger).Spec.JobTemplate.Spec.Template
+	levelVersion := psapi.LevelVersion{Level: psapi.LevelRestricted, Version: psapi.LatestVersion()}
+	var violations []string
+	for _, result := range evaluator.EvaluatePod(levelVersion, &template.ObjectMeta, &template.Spec) {
+		if !result.Allowed {
+			violations = append(violations, result.ForbiddenReason+": "+result.ForbiddenDetail)
+		}
+	}
+	return violations
+}
+
+func Test_constructPodSpec_restricted(t *testing.T) {
+	pinger := devorgv1.CoinbasePinger{Spec: devorgv1.CoinbasePingerSpec{Interval: "5m"}}
+	if violations := restrictedViolations(t, pinger); len(violations) > 0 {
+		t.Errorf("Pinger pods violate the restricted profile: %v", violations)
+	}
+
+	spec := constructPodSpec(pinger, DefaultOperatorConfig().Pinger)
+	if spec.AutomountServiceAccountToken == nil || *spec.AutomountServiceAccountToken {
+		t.Errorf("Got automountServiceAccountToken %v, want false", spec.AutomountServiceAccountToken)
+	}
+	container := spec.Containers[0]
+	if container.Resources.Limits.Memory().IsZero() || container.Resources.Requests.Cpu().IsZero() {
+		t.Errorf("Got resources %+v, want requests and limits", container.Resources)
+	}
+	mounted := false
+	for _, mount := range container.VolumeMounts {
+		mounted = mounted || mount.MountPath == serviceAccountTokenPath
+	}
+	if !mounted {
+		t.Errorf("Got mounts %+v, want the projected token at %s", container.VolumeMounts, serviceAccountTokenPath)
+	}
+}
+
+func Test_constructPodSpec_relaxed(t *testing.T) {
+	pinger := devorgv1.CoinbasePinger{Spec: devorgv1.CoinbasePingerSpec{
+		Interval: "5m",
+		SecurityContext: &v1.SecurityContext{
+			ReadOnlyRootFilesystem: pointer.BoolPtr(false),
+		},
+		Resources: &v1.ResourceRequirements{
+			Limits: v1.ResourceList{v1.ResourceMemory: resource.MustParse("256Mi")},
+		},
+	}}
+	if violations := restrictedViolations(t, pinger); len(violations) == 0 {
+		t.Errorf("Relaxed pinger pods pass the restricted profile")
+	}
+
+	container := constructPodSpec(pinger, DefaultOperatorConfig().Pinger).Containers[0]
+	if *container.SecurityContext.ReadOnlyRootFilesystem || container.SecurityContext.Capabilities != nil {
+		t.Errorf("Got security context %+v, want the spec one", container.SecurityContext)
+	}
+	if !container.Resources.Limits.Memory().Equal(resource.MustParse("256Mi")) || len(container.Resources.Requests) > 0 {
+		t.Errorf("Got resources %+v, want the spec ones", container.Resources)
+	}
+	pinger.Spec.SecurityContext.ReadOnlyRootFilesystem = pointer.BoolPtr(true)
+	if *container.SecurityContext.ReadOnlyRootFilesystem {
+		t.Errorf("Pod spec shares pointers with the pinger spec")
+	}
+}
@@ -1,34 +1,72 @@
 package controllers
 
 import (
+	"encoding/json"
+	"strings"
+	"time"
+
 	"github.com/go-logr/logr"
 	devorgv1 "github.com/kalynv/coinbase-pinger/operator/api/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
-const (
-	TypeLabel          string = "type"
-	StatusLabel        string = "status"
-	ReasonLabel        string = "reason"
-	MessageAnnotation  string = "message"
-	PingTimeAnnotation string = "ping-time"
-)
+// terminationResult is the compact JSON result the pinger writes to its
+// container termination message.
+type terminationResult struct {
+	Type             string      `json:"type"`
+	Status           bool        `json:"status"`
+	Reason           string      `json:"reason"`
+	Message          string      `json:"message"`
+	PingTime         metav1.Time `json:"pingTime"`
+	FailedAssertions []string    `json:"failedAssertions,omitempty"`
+	FailedHop        string      `json:"failedHop,omitempty"`
+}
+
+// podToCondition reads the ping result of a pod from its metadata, then from
+// its termination message, and finally classifies pinger pod failures. It
+// returns false for pods which are still running the ping.
+func podToCondition(
+	pod corev1.Pod,
+	now time.Time,
+	maxPending time.Duration,
+	l logr.Logger,
+) (devorgv1.Condition, bool) {
+	labels := pod.GetLabels()
+	annotations := pod.GetAnnotations()
+	if labels[devorgv1.TypeLabel] != "" && annotations[devorgv1.PingTimeAnnotation] != "" {
+		return metadataCondition(pod, l), true
+	}
+	if condition, found := terminationMessageCondition(pod, l); found {
+		return condition, true
+	}
+	if condition, failed := podFailureCondition(pod, now, maxPending); failed {
+		return condition, true
+	}
+	if labels[devorgv1.TypeLabel] != "" {
+		return metadataCondition(pod, l), true
+	}
+	return devorgv1.Condition{}, false
+}
 
-func podToCondition(pod corev1.Pod, l logr.Logger) devorgv1.Condition {
+func metadataCondition(pod corev1.Pod, l logr.Logger) devorgv1.Condition {
 	condition := devorgv1.Condition{}
 	labels := pod.GetLabels()
 	annotations := pod.GetAnnotations()
 	if labels != nil {
-		condition.Type = labels[TypeLabel]
-		if labels[StatusLabel] == "true" {
+		condition.Type = labels[devorgv1.TypeLabel]
+		if labels[devorgv1.StatusLabel] == "true" {
 			condition.Status = true
 		}
-		condition.Reason = labels[ReasonLabel]
+		condition.Reason = labels[devorgv1.ReasonLabel]
 	}
 	if annotations != nil {
-		condition.Message = annotations[MessageAnnotation]
-		pingTime := annotations[PingTimeAnnotation]
+		condition.Message = annotations[devorgv1.MessageAnnotation]
+		if failed := annotations[devorgv1.FailedAssertionsAnnotation]; failed != "" {
+			condition.FailedAssertions = strings.Split(failed, "\n")
+		}
+		condition.FailedHop = annotations[devorgv1.FailedHopAnnotation]
+		pingTime := annotations[devorgv1.PingTimeAnnotation]
 		t := metav1.Time{}
 		e := t.UnmarshalJSON([]byte(pingTime))
 		if e != nil {
@@ -47,15 +85,52 @@ func podToCondition(pod corev1.Pod, l logr.Logger) devorgv1.Condition {
 	return condition
 }
 
-func podsToConditions(pods []corev1.Pod, l logr.Logger) []devorgv1.Condition {
+// terminationMessageCondition reads the ping result from the pinger
+// container termination message. It is the fallback for pods whose labels
+// and annotations were never updated.
+func terminationMessageCondition(pod corev1.Pod, l logr.Logger) (devorgv1.Condition, bool) {
+	for _, status := range pod.Status.ContainerStatuses {
+		terminated := status.State.Terminated
+		if terminated == nil || terminated.Message == "" {
+			continue
+		}
+		result := terminationResult{}
+		if err := json.Unmarshal([]byte(terminated.Message), &result); err != nil {
+			l.Error(
+				err,
+				"Could not unmarshal termination message",
+				"pod",
+				pod.Name,
+				"container",
+				status.Name,
+			)
+			continue
+		}
+		return devorgv1.Condition{
+			Type:             result.Type,
+			Status:           result.Status,
+			Reason:           result.Reason,
+			Message:          result.Message,
+			PingTime:         result.PingTime,
+			FailedAssertions: result.FailedAssertions,
+			FailedHop:        result.FailedHop,
+		}, true
+	}
+	return devorgv1.Condition{}, false
+}
+
+func podsToConditions(pods []corev1.Pod, maxPending time.Duration, l logr.Logger) []devorgv1.Condition {
 	podsNumber := len(pods)
 	if podsNumber == 0 {
 		return nil
 	}
+	now := time.Now()
 	conditions := make([]devorgv1.Condition, 0, podsNumber)
 	for _, pod := range pods {
-		condition := podToCondition(pod, l)
-		conditions = append(conditions, condition)
+		if condition, done := podToCondition(pod, now, maxPending, l); done {
+			condition.Source = pod.Name
+			conditions = append(conditions, condition)
+		}
 	}
 	return conditions
 }
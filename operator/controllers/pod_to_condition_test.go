@@ -0,0 +1,102 @@
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	devorgv1 "github.com/kalynv/coinbase-pinger/operator/api/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+func Test_podToCondition(t *testing.T) {
+	pingTime := metav1.NewTime(time.Date(2021, 9, 1, 12, 0, 0, 0, time.UTC))
+	terminated := func(message string) corev1.PodStatus {
+		return corev1.PodStatus{
+			ContainerStatuses: []corev1.ContainerStatus{
+				{
+					Name: "pinger",
+					State: corev1.ContainerState{
+						Terminated: &corev1.ContainerStateTerminated{Message: message},
+					},
+				},
+			},
+		}
+	}
+
+	tests := []struct {
+		name     string
+		pod      corev1.Pod
+		want     devorgv1.Condition
+		wantDone bool
+	}{
+		{
+			name: "result in metadata",
+			pod: corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{
+						devorgv1.TypeLabel:   "ServiceOnline",
+						devorgv1.StatusLabel: "true",
+						devorgv1.ReasonLabel: "PingSucceeded",
+					},
+					Annotations: map[string]string{
+						devorgv1.MessageAnnotation:  "{}",
+						devorgv1.PingTimeAnnotation: `"2021-09-01T12:00:00Z"`,
+					},
+				},
+				Status: terminated(`{"type":"ServiceOffline"}`),
+			},
+			want: devorgv1.Condition{
+				Type:     "ServiceOnline",
+				Status:   true,
+				Reason:   "PingSucceeded",
+				Message:  "{}",
+				PingTime: pingTime,
+			},
+			wantDone: true,
+		},
+		{
+			name: "result in termination message only",
+			pod: corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{devorgv1.CRD_UID: "uid"},
+				},
+				Status: terminated(`{"type":"ServiceOnline","status":false,"reason":"AssertionFailed",` +
+					`"message":"{}","pingTime":"2021-09-01T12:00:00Z","failedAssertions":["status=2xx"]}`),
+			},
+			want: devorgv1.Condition{
+				Type:             "ServiceOnline",
+				Reason:           "AssertionFailed",
+				Message:          "{}",
+				PingTime:         pingTime,
+				FailedAssertions: []string{"status=2xx"},
+			},
+			wantDone: true,
+		},
+		{
+			name: "unparsable termination message of a running pod",
+			pod: corev1.Pod{
+				Status: terminated("panic: something"),
+			},
+			want: devorgv1.Condition{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, done := podToCondition(tt.pod, time.Now(), maxPendingTimeout, log.Log)
+			if done != tt.wantDone {
+				t.Errorf("Got done %v, want %v", done, tt.wantDone)
+			}
+			if got.Type != tt.want.Type ||
+				got.Status != tt.want.Status ||
+				got.Reason != tt.want.Reason ||
+				got.Message != tt.want.Message ||
+				!got.PingTime.Equal(&tt.want.PingTime) ||
+				len(got.FailedAssertions) != len(tt.want.FailedAssertions) {
+				t.Errorf("Got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
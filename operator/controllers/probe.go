@@ -0,0 +1,132 @@
+package controllers
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+
+	configv1alpha1 "github.com/kalynv/coinbase-pinger/operator/api/config/v1alpha1"
+	devorgv1 "github.com/kalynv/coinbase-pinger/operator/api/v1"
+)
+
+// probeType returns the probe type of the pinger, http by default.
+func probeType(pinger devorgv1.CoinbasePinger) devorgv1.ProbeType {
+	if pinger.Spec.Probe == nil || pinger.Spec.Probe.Type == "" {
+		return devorgv1.HTTPProbe
+	}
+	return pinger.Spec.Probe.Type
+}
+
+// probeArgs returns the pinger arguments selecting the probe and its
+// settings. HTTP probes keep pinging the endpoint of the base URL.
+func probeArgs(pinger devorgv1.CoinbasePinger) []string {
+	probe := pinger.Spec.Probe
+	kind := probeType(pinger)
+	if kind == devorgv1.HTTPProbe {
+		return []string{pingerEndpoint(pinger)}
+	}
+	args := []string{"--probe=" + string(kind), "--url=" + probe.Target}
+	switch {
+	case kind == devorgv1.DNSProbe && probe.DNS != nil:
+		if probe.DNS.RecordType != "" {
+			args = append(args, "--dns-record-type="+probe.DNS.RecordType)
+		}
+		if probe.DNS.Server != "" {
+			args = append(args, "--dns-server="+probe.DNS.Server)
+		}
+	case kind == devorgv1.TLSProbe && probe.TLS != nil:
+		if probe.TLS.ServerName != "" {
+			args = append(args, "--tls-server-name="+probe.TLS.ServerName)
+		}
+		if probe.TLS.MinValidity != nil {
+			args = append(args, "--tls-min-validity="+probe.TLS.MinValidity.Duration.String())
+		}
+	case kind == devorgv1.GRPCProbe && probe.GRPC != nil:
+		if probe.GRPC.Service != "" {
+			args = append(args, "--grpc-service="+probe.GRPC.Service)
+		}
+		args = append(args, "--grpc-tls="+strconv.FormatBool(probe.GRPC.TLS))
+	case kind == devorgv1.WebSocketProbe && probe.WebSocket != nil:
+		args = append(args, webSocketArgs(probe.WebSocket)...)
+	}
+	return args
+}
+
+// pingerEndpoint returns the path HTTP probes request. It is always taken
+// relative to the base URL, so the endpoint can not bypass the allowed base
+// URL domains.
+func pingerEndpoint(pinger devorgv1.CoinbasePinger) string {
+	if pinger.Spec.Endpoint == "" {
+		return devorgv1.DefaultEndpoint
+	}
+	return "/" + strings.TrimPrefix(pinger.Spec.Endpoint, "/")
+}
+
+func webSocketArgs(spec *devorgv1.WebSocketProbeSpec) []string {
+	var args []string
+	if spec.Subscribe != "" {
+		args = append(args, "--ws-subscribe="+spec.Subscribe)
+	}
+	if spec.Messages != nil {
+		args = append(args, "--ws-messages="+strconv.Itoa(int(*spec.Messages)))
+	}
+	if spec.Within != nil {
+		args = append(args, "--ws-within="+spec.Within.Duration.String())
+	}
+	if spec.MaxStaleness != nil {
+		args = append(args, "--ws-max-staleness="+spec.MaxStaleness.Duration.String())
+	}
+	if spec.TimeField != "" {
+		args = append(args, "--ws-time-field="+spec.TimeField)
+	}
+	return args
+}
+
+// pingerTarget returns the URL the pinger connects to, with an explicit
+// port for probes other than http. It is empty for dns probes asking the
+// cluster DNS, which pinger pods may always reach.
+func pingerTarget(pinger devorgv1.CoinbasePinger, defaults configv1alpha1.PingerDefaults) (string, error) {
+	probe := pinger.Spec.Probe
+	kind := probeType(pinger)
+	switch kind {
+	case devorgv1.HTTPProbe:
+		return pingerBaseURL(pinger, defaults), nil
+	case devorgv1.DNSProbe:
+		if probe.Target == "" {
+			return "", fmt.Errorf("dns probe has no target name")
+		}
+		if probe.DNS == nil || probe.DNS.Server == "" {
+			return "", nil
+		}
+		return targetURL(kind, probe.DNS.Server, "53")
+	case devorgv1.TLSProbe:
+		return targetURL(kind, probe.Target, "443")
+	case devorgv1.GRPCProbe:
+		if probe.GRPC != nil && probe.GRPC.TLS {
+			return targetURL(kind, probe.Target, "443")
+		}
+	case devorgv1.WebSocketProbe:
+		if target, err := url.Parse(probe.Target); err != nil || (target.Scheme != "ws" && target.Scheme != "wss") {
+			return "", fmt.Errorf("websocket probe target %q must be a ws or wss URL", probe.Target)
+		}
+		return probe.Target, nil
+	}
+	return targetURL(kind, probe.Target, "")
+}
+
+// targetURL returns the host[:port] target as a URL with a port.
+func targetURL(kind devorgv1.ProbeType, target string, defaultPort string) (string, error) {
+	if target == "" {
+		return "", fmt.Errorf("%s probe has no target", kind)
+	}
+	host := target
+	if _, _, err := net.SplitHostPort(target); err != nil {
+		if defaultPort == "" {
+			return "", fmt.Errorf("%s probe target %q must be host:port", kind, target)
+		}
+		host = net.JoinHostPort(target, defaultPort)
+	}
+	return string(kind) + "://" + host, nil
+}
@@ -0,0 +1,117 @@
+package controllers
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	devorgv1 "github.com/kalynv/coinbase-pinger/operator/api/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+)
+
+func Test_probeArgs(t *testing.T) {
+	tests := []struct {
+		endpoint string
+		probe    *devorgv1.ProbeSpec
+		expected string
+	}{
+		{probe: nil, expected: "/prices/BTC-USD/buy"},
+		{endpoint: "/prices/ETH-USD/spot", probe: nil, expected: "/prices/ETH-USD/spot"},
+		{endpoint: "time", probe: &devorgv1.ProbeSpec{Type: devorgv1.HTTPProbe}, expected: "/time"},
+		{probe: &devorgv1.ProbeSpec{Type: devorgv1.HTTPProbe}, expected: "/prices/BTC-USD/buy"},
+		{
+			probe:    &devorgv1.ProbeSpec{Type: devorgv1.TCPProbe, Target: "gateway.internal:5432"},
+			expected: "--probe=tcp --url=gateway.internal:5432",
+		},
+		{
+			probe: &devorgv1.ProbeSpec{
+				Type:   devorgv1.DNSProbe,
+				Target: "api.example.org",
+				DNS:    &devorgv1.DNSProbeSpec{RecordType: "AAAA", Server: "10.0.0.10"},
+			},
+			expected: "--probe=dns --url=api.example.org --dns-record-type=AAAA --dns-server=10.0.0.10",
+		},
+		{
+			probe: &devorgv1.ProbeSpec{
+				Type:   devorgv1.TLSProbe,
+				Target: "api.example.org",
+				TLS:    &devorgv1.TLSProbeSpec{MinValidity: &metav1.Duration{Duration: 7 * 24 * time.Hour}},
+			},
+			expected: "--probe=tls --url=api.example.org --tls-min-validity=168h0m0s",
+		},
+		{
+			probe: &devorgv1.ProbeSpec{
+				Type:   devorgv1.GRPCProbe,
+				Target: "prices.internal:9090",
+				GRPC:   &devorgv1.GRPCProbeSpec{Service: "prices"},
+			},
+			expected: "--probe=grpc --url=prices.internal:9090 --grpc-service=prices --grpc-tls=false",
+		},
+		{
+			probe: &devorgv1.ProbeSpec{
+				Type:   devorgv1.WebSocketProbe,
+				Target: "wss://ws-feed.exchange.coinbase.com",
+				WebSocket: &devorgv1.WebSocketProbeSpec{
+					Subscribe:    `{"type":"subscribe","product_ids":["BTC-USD"],"channels":["ticker"]}`,
+					Messages:     pointer.Int32Ptr(3),
+					MaxStaleness: &metav1.Duration{Duration: 5 * time.Second},
+				},
+			},
+			expected: "--probe=websocket --url=wss://ws-feed.exchange.coinbase.com " +
+				`--ws-subscribe={"type":"subscribe","product_ids":["BTC-USD"],"channels":["ticker"]} ` +
+				"--ws-messages=3 --ws-max-staleness=5s",
+		},
+	}
+	for _, tt := range tests {
+		pinger := devorgv1.CoinbasePinger{Spec: devorgv1.CoinbasePingerSpec{Endpoint: tt.endpoint, Probe: tt.probe}}
+		if got := strings.Join(probeArgs(pinger), " "); got != tt.expected {
+			t.Errorf("Got args [%s], want [%s]", got, tt.expected)
+		}
+	}
+}
+
+func Test_pingerTarget(t *testing.T) {
+	tests := []struct {
+		probe     *devorgv1.ProbeSpec
+		expected  string
+		wantError bool
+	}{
+		{probe: nil, expected: "https://api.coinbase.com/v2"},
+		{probe: &devorgv1.ProbeSpec{Type: devorgv1.TCPProbe, Target: "10.0.0.5:5432"}, expected: "tcp://10.0.0.5:5432"},
+		{probe: &devorgv1.ProbeSpec{Type: devorgv1.TCPProbe, Target: "10.0.0.5"}, wantError: true},
+		{probe: &devorgv1.ProbeSpec{Type: devorgv1.TLSProbe, Target: "api.example.org"}, expected: "tls://api.example.org:443"},
+		{probe: &devorgv1.ProbeSpec{Type: devorgv1.DNSProbe, Target: "api.example.org"}, expected: ""},
+		{
+			probe: &devorgv1.ProbeSpec{
+				Type:   devorgv1.DNSProbe,
+				Target: "api.example.org",
+				DNS:    &devorgv1.DNSProbeSpec{Server: "1.1.1.1"},
+			},
+			expected: "dns://1.1.1.1:53",
+		},
+		{probe: &devorgv1.ProbeSpec{Type: devorgv1.DNSProbe}, wantError: true},
+		{
+			probe: &devorgv1.ProbeSpec{
+				Type:   devorgv1.GRPCProbe,
+				Target: "prices.example.org",
+				GRPC:   &devorgv1.GRPCProbeSpec{TLS: true},
+			},
+			expected: "grpc://prices.example.org:443",
+		},
+		{probe: &devorgv1.ProbeSpec{Type: devorgv1.GRPCProbe, Target: "prices.internal"}, wantError: true},
+		{
+			probe:    &devorgv1.ProbeSpec{Type: devorgv1.WebSocketProbe, Target: "wss://ws-feed.exchange.coinbase.com"},
+			expected: "wss://ws-feed.exchange.coinbase.com",
+		},
+		{probe: &devorgv1.ProbeSpec{Type: devorgv1.WebSocketProbe, Target: "https://api.coinbase.com"}, wantError: true},
+	}
+	defaults := DefaultOperatorConfig().Pinger
+	for _, tt := range tests {
+		pinger := devorgv1.CoinbasePinger{Spec: devorgv1.CoinbasePingerSpec{Probe: tt.probe}}
+		got, err := pingerTarget(pinger, defaults)
+		if got != tt.expected || (err != nil) != tt.wantError {
+			t.Errorf("Got target [%s] and error [%v] for %+v, want [%s] and error %v", got, err, tt.probe, tt.expected, tt.wantError)
+		}
+	}
+}
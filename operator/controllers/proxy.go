@@ -0,0 +1,103 @@
+package controllers
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	configv1alpha1 "github.com/kalynv/coinbase-pinger/operator/api/config/v1alpha1"
+	devorgv1 "github.com/kalynv/coinbase-pinger/operator/api/v1"
+	"golang.org/x/net/http/httpproxy"
+	v1 "k8s.io/api/core/v1"
+)
+
+const (
+	// ProxyUsernameKey and ProxyPasswordKey are the keys of the proxy
+	// credentials Secret.
+	ProxyUsernameKey string = "username"
+	ProxyPasswordKey string = "password"
+)
+
+// proxyAllowed reports proxy URLs with credentials, which belong in a
+// Secret, and proxies the egress allowlist does not allow.
+func proxyAllowed(config *configv1alpha1.OperatorConfig, proxy *devorgv1.ProxySpec) error {
+	if proxy == nil {
+		return nil
+	}
+	parsed, err := url.Parse(proxy.URL)
+	if err != nil {
+		return fmt.Errorf("invalid proxy URL %q: %w", proxy.URL, err)
+	}
+	if parsed.User != nil {
+		return fmt.Errorf("proxy URL %s has credentials, set credentialsSecretName instead", parsed.Redacted())
+	}
+	if err := baseURLAllowed(config, proxy.URL); err != nil {
+		return fmt.Errorf("proxy not allowed: %w", err)
+	}
+	return nil
+}
+
+// usesProxy tells whether probes of the type go through the proxy.
+func usesProxy(kind devorgv1.ProbeType) bool {
+	return kind == devorgv1.HTTPProbe || kind == devorgv1.WebSocketProbe
+}
+
+// proxyEnv returns the environment the pinger picks its proxy up from.
+func proxyEnv(proxy *devorgv1.ProxySpec) []v1.EnvVar {
+	if proxy == nil {
+		return nil
+	}
+	env := []v1.EnvVar{
+		{Name: "HTTP_PROXY", Value: proxy.URL},
+		{Name: "HTTPS_PROXY", Value: proxy.URL},
+	}
+	if len(proxy.NoProxy) > 0 {
+		env = append(env, v1.EnvVar{Name: "NO_PROXY", Value: strings.Join(proxy.NoProxy, ",")})
+	}
+	if proxy.CredentialsSecretName != "" {
+		env = append(env,
+			secretEnvVar("PROXY_USERNAME", proxy.CredentialsSecretName, ProxyUsernameKey),
+			secretEnvVar("PROXY_PASSWORD", proxy.CredentialsSecretName, ProxyPasswordKey),
+		)
+	}
+	return env
+}
+
+func secretEnvVar(name string, secretName string, key string) v1.EnvVar {
+	return v1.EnvVar{
+		Name: name,
+		ValueFrom: &v1.EnvVarSource{
+			SecretKeyRef: &v1.SecretKeySelector{
+				LocalObjectReference: v1.LocalObjectReference{Name: secretName},
+				Key:                  key,
+			},
+		},
+	}
+}
+
+// proxyBypassed tells whether the pinger reaches baseURL directly, because
+// it has no proxy or the base URL matches its no-proxy list. It decides
+// like the pinger does.
+func proxyBypassed(proxy *devorgv1.ProxySpec, baseURL string) bool {
+	if proxy == nil {
+		return true
+	}
+	target, err := url.Parse(baseURL)
+	if err != nil {
+		return false
+	}
+	// the pinger looks the proxy of feeds up by their http form
+	switch target.Scheme {
+	case "ws":
+		target.Scheme = "http"
+	case "wss":
+		target.Scheme = "https"
+	}
+	config := httpproxy.Config{
+		HTTPProxy:  proxy.URL,
+		HTTPSProxy: proxy.URL,
+		NoProxy:    strings.Join(proxy.NoProxy, ","),
+	}
+	proxyURL, err := config.ProxyFunc()(target)
+	return err == nil && proxyURL == nil
+}
@@ -0,0 +1,88 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	devorgv1 "github.com/kalynv/coinbase-pinger/operator/api/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func Test_rejectPinger(t *testing.T) {
+	ctx := context.Background()
+	pinger := &devorgv1.CoinbasePinger{
+		ObjectMeta: metav1.ObjectMeta{Name: "btc", Namespace: "team", UID: types.UID("uid")},
+		Spec:       devorgv1.CoinbasePingerSpec{Interval: "5m"},
+	}
+	r := newRBACTestReconciler(t, pinger)
+	cronJob, err := r.desiredCronJob(pinger, "btc-pinger", DefaultOperatorConfig().Pinger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Create(ctx, cronJob); err != nil {
+		t.Fatal(err)
+	}
+
+	rejectErr := errors.New(`host "api.coinbase.com" of base URL "https://api.coinbase.com/v2" is not allowed`)
+	for i := 0; i < 2; i++ {
+		if _, err := r.rejectPinger(ctx, pinger, "BaseURLNotAllowed", rejectErr); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := r.Get(ctx, client.ObjectKeyFromObject(cronJob), cronJob); err != nil {
+		t.Fatal(err)
+	}
+	if cronJob.Spec.Suspend == nil || !*cronJob.Spec.Suspend {
+		t.Errorf("Got CronJob suspend %v, want true", cronJob.Spec.Suspend)
+	}
+
+	stored := &devorgv1.CoinbasePinger{}
+	if err := r.Get(ctx, client.ObjectKeyFromObject(pinger), stored); err != nil {
+		t.Fatal(err)
+	}
+	conditions := stored.Status.Conditions
+	if len(conditions) != 1 {
+		t.Fatalf("Got %d conditions, want 1: %v", len(conditions), conditions)
+	}
+	if conditions[0].Type != devorgv1.PingerRejected || conditions[0].Reason != "BaseURLNotAllowed" {
+		t.Errorf("Got condition %s/%s, want %s/BaseURLNotAllowed",
+			conditions[0].Type, conditions[0].Reason, devorgv1.PingerRejected)
+	}
+	if events := len(r.Recorder.(*record.FakeRecorder).Events); events != 1 {
+		t.Errorf("Got %d events, want 1", events)
+	}
+
+	// accepting the pinger again resumes the CronJob
+	accepted, err := r.desiredCronJob(pinger, cronJob.Name, DefaultOperatorConfig().Pinger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cronjobChanged(cronJob, accepted) {
+		t.Error("Got suspended CronJob unchanged, want it resumed")
+	}
+}
+
+func Test_rejectPinger_withoutCronJob(t *testing.T) {
+	ctx := context.Background()
+	pinger := &devorgv1.CoinbasePinger{
+		ObjectMeta: metav1.ObjectMeta{Name: "btc", Namespace: "team", UID: types.UID("uid")},
+		Spec:       devorgv1.CoinbasePingerSpec{Interval: "30s"},
+	}
+	r := newRBACTestReconciler(t, pinger)
+	if _, err := r.rejectPinger(ctx, pinger, "InvalidInterval", errors.New("interval too short")); err != nil {
+		t.Fatal(err)
+	}
+	cronJobs := batchv1.CronJobList{}
+	if err := r.List(ctx, &cronJobs); err != nil {
+		t.Fatal(err)
+	}
+	if len(cronJobs.Items) != 0 {
+		t.Errorf("Got %d CronJobs, want none", len(cronJobs.Items))
+	}
+}
@@ -0,0 +1,74 @@
+package controllers
+
+import (
+	"sort"
+
+	devorgv1 "github.com/kalynv/coinbase-pinger/operator/api/v1"
+)
+
+// maxStatusHistory is the number of ping results kept in the status.
+const maxStatusHistory = 20
+
+// mergeConditions adds results collected from pods to the status history,
+// so results survive removal of their pods. A result from a pod already in
+// the history replaces the older one, as pods may report more details
+// later. The newest limit results are kept, oldest first.
+func mergeConditions(history, collected []devorgv1.Condition, limit int) []devorgv1.Condition {
+	merged := make([]devorgv1.Condition, 0, len(history)+len(collected))
+	bySource := map[string]int{}
+	for _, condition := range history {
+		if condition.Source != "" {
+			bySource[condition.Source] = len(merged)
+		}
+		merged = append(merged, condition)
+	}
+	for _, condition := range collected {
+		if i, found := bySource[condition.Source]; found && condition.Source != "" {
+			merged[i] = condition
+			continue
+		}
+		if i, found := findLegacy(merged, condition); found {
+			merged[i] = condition
+			continue
+		}
+		merged = append(merged, condition)
+	}
+
+	sortConditions(merged)
+	if len(merged) > limit {
+		merged = merged[len(merged)-limit:]
+	}
+	return merged
+}
+
+// findLegacy finds the result recorded before conditions had a source.
+func findLegacy(history []devorgv1.Condition, condition devorgv1.Condition) (int, bool) {
+	for i, recorded := range history {
+		if recorded.Source == "" &&
+			recorded.Type == condition.Type &&
+			recorded.Reason == condition.Reason &&
+			recorded.PingTime.Equal(&condition.PingTime) {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// sortConditions orders results by ping time. Ties are broken by source,
+// type and reason, so the same results always serialize the same way and
+// unchanged status is not written again.
+func sortConditions(conditions []devorgv1.Condition) {
+	sort.SliceStable(conditions, func(i, j int) bool {
+		a, b := conditions[i], conditions[j]
+		if !a.PingTime.Equal(&b.PingTime) {
+			return a.PingTime.Before(&b.PingTime)
+		}
+		if a.Source != b.Source {
+			return a.Source < b.Source
+		}
+		if a.Type != b.Type {
+			return a.Type < b.Type
+		}
+		return a.Reason < b.Reason
+	})
+}
@@ -0,0 +1,74 @@
+package controllers
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	devorgv1 "github.com/kalynv/coinbase-pinger/operator/api/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func Test_mergeConditions(t *testing.T) {
+	at := func(minute int) metav1.Time {
+		return metav1.NewTime(time.Date(2021, 9, 1, 12, minute, 0, 0, time.UTC))
+	}
+	result := func(source string, minute int, reason string) devorgv1.Condition {
+		return devorgv1.Condition{Source: source, PingTime: at(minute), Reason: reason}
+	}
+
+	tests := []struct {
+		name      string
+		history   []devorgv1.Condition
+		collected []devorgv1.Condition
+		limit     int
+		want      []string
+	}{
+		{
+			name:      "results of removed pods are kept",
+			history:   []devorgv1.Condition{result("a", 1, "PingSucceeded")},
+			collected: []devorgv1.Condition{result("b", 2, "PingSucceeded")},
+			limit:     10,
+			want:      []string{"a", "b"},
+		},
+		{
+			name:      "newer result of the same pod replaces the older one",
+			history:   []devorgv1.Condition{result("a", 1, "PingFailed")},
+			collected: []devorgv1.Condition{result("a", 1, "AssertionFailed")},
+			limit:     10,
+			want:      []string{"a:AssertionFailed"},
+		},
+		{
+			name:      "result recorded before sources existed",
+			history:   []devorgv1.Condition{result("", 1, "PingSucceeded")},
+			collected: []devorgv1.Condition{result("a", 1, "PingSucceeded")},
+			limit:     10,
+			want:      []string{"a"},
+		},
+		{
+			name: "oldest results are dropped",
+			history: []devorgv1.Condition{
+				result("c", 3, "PingSucceeded"),
+				result("a", 1, "PingSucceeded"),
+			},
+			collected: []devorgv1.Condition{result("b", 2, "PingSucceeded")},
+			limit:     2,
+			want:      []string{"b", "c"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := mergeConditions(tt.history, tt.collected, tt.limit)
+			if len(got) != len(tt.want) {
+				t.Fatalf("Got %d conditions %+v, want %v", len(got), got, tt.want)
+			}
+			for i, want := range tt.want {
+				parts := strings.SplitN(want, ":", 2)
+				if got[i].Source != parts[0] || (len(parts) == 2 && got[i].Reason != parts[1]) {
+					t.Errorf("Got condition %d %+v, want %s", i, got[i], want)
+				}
+			}
+		})
+	}
+}
@@ -17,6 +17,9 @@ limitations under the License.
 package controllers
 
 import (
+	"context"
+	"io/ioutil"
+	"os"
 	"path/filepath"
 	"testing"
 
@@ -24,12 +27,15 @@ import (
 	. "github.com/onsi/gomega"
 	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/rest"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/envtest"
 	"sigs.k8s.io/controller-runtime/pkg/envtest/printer"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 
+	configv1alpha1 "github.com/kalynv/coinbase-pinger/operator/api/config/v1alpha1"
 	batchv1 "github.com/kalynv/coinbase-pinger/operator/api/v1"
 	//+kubebuilder:scaffold:imports
 )
@@ -40,6 +46,18 @@ import (
 var cfg *rest.Config
 var k8sClient client.Client
 var testEnv *envtest.Environment
+var cancelManager context.CancelFunc
+
+// operatorConfigPath is the operator config file the reconciler watches,
+// specs write it to change the config.
+var operatorConfigPath string
+
+// suiteOperatorConfig allows the default base URL.
+const suiteOperatorConfig = `apiVersion: config.dev.org/v1alpha1
+kind: OperatorConfig
+allowedBaseURLDomains:
+- coinbase.com
+`
 
 func TestAPIs(t *testing.T) {
 	RegisterFailHandler(Fail)
@@ -58,23 +76,58 @@ var _ = BeforeSuite(func() {
 		ErrorIfCRDPathMissing: true,
 	}
 
-	cfg, err := testEnv.Start()
+	var err error
+	cfg, err = testEnv.Start()
 	Expect(err).NotTo(HaveOccurred())
 	Expect(cfg).NotTo(BeNil())
 
 	err = batchv1.AddToScheme(scheme.Scheme)
 	Expect(err).NotTo(HaveOccurred())
 
+	err = configv1alpha1.AddToScheme(scheme.Scheme)
+	Expect(err).NotTo(HaveOccurred())
+
 	//+kubebuilder:scaffold:scheme
 
 	k8sClient, err = client.New(cfg, client.Options{Scheme: scheme.Scheme})
 	Expect(err).NotTo(HaveOccurred())
 	Expect(k8sClient).NotTo(BeNil())
 
+	mgr, err := ctrl.NewManager(cfg, ctrl.Options{
+		Scheme:             scheme.Scheme,
+		MetricsBindAddress: "0",
+		NewCache:           NewCache(cache.Options{}, nil),
+	})
+	Expect(err).NotTo(HaveOccurred())
+
+	configDir, err := ioutil.TempDir("", "operator-config")
+	Expect(err).NotTo(HaveOccurred())
+	operatorConfigPath = filepath.Join(configDir, "controller_manager_config.yaml")
+	Expect(ioutil.WriteFile(operatorConfigPath, []byte(suiteOperatorConfig), 0o600)).To(Succeed())
+	operatorConfig, err := NewConfigStore(operatorConfigPath, scheme.Scheme)
+	Expect(err).NotTo(HaveOccurred())
+
+	err = (&CoinbasePingerReconciler{
+		Client:    mgr.GetClient(),
+		Scheme:    mgr.GetScheme(),
+		Recorder:  mgr.GetEventRecorderFor("coinbasepinger-controller"),
+		Config:    operatorConfig,
+		APIReader: mgr.GetAPIReader(),
+	}).SetupWithManager(mgr)
+	Expect(err).NotTo(HaveOccurred())
+
+	var ctx context.Context
+	ctx, cancelManager = context.WithCancel(context.Background())
+	go func() {
+		defer GinkgoRecover()
+		Expect(mgr.Start(ctx)).To(Succeed())
+	}()
 }, 60)
 
 var _ = AfterSuite(func() {
 	By("tearing down the test environment")
+	cancelManager()
+	os.RemoveAll(filepath.Dir(operatorConfigPath))
 	err := testEnv.Stop()
 	Expect(err).NotTo(HaveOccurred())
 })
@@ -3,11 +3,16 @@ module github.com/kalynv/coinbase-pinger/operator
 go 1.16
 
 require (
+	github.com/fsnotify/fsnotify v1.4.9
 	github.com/go-logr/logr v0.4.0
 	github.com/onsi/ginkgo v1.16.4
 	github.com/onsi/gomega v1.15.0
-	k8s.io/api v0.22.1
-	k8s.io/apimachinery v0.22.1
-	k8s.io/client-go v0.22.1
+	github.com/prometheus/client_golang v1.11.0
+	golang.org/x/net v0.0.0-20211209124913-491a49abca63
+	k8s.io/api v0.22.6
+	k8s.io/apimachinery v0.22.6
+	k8s.io/client-go v0.22.6
+	k8s.io/pod-security-admission v0.22.6
+	k8s.io/utils v0.0.0-20210819203725-bdf08cb9a70a
 	sigs.k8s.io/controller-runtime v0.10.0
 )